@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/cd1989/harbor-cleaner/pkg/cleaner"
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+	"github.com/cd1989/harbor-cleaner/pkg/trigger"
+	"github.com/cd1989/harbor-cleaner/pkg/version"
+)
+
+// Process exit codes other than those reflecting clean result, see cleaner.ExitSuccess etc.
+const (
+	ExitSuccess = cleaner.ExitSuccess
+	ExitError   = 1
+	ExitUsage   = 64
+)
+
+// command is a sub command of the cleaner.
+type command struct {
+	name  string
+	short string
+	// flags registers flags specific to the command.
+	flags func(fs *flag.FlagSet)
+	// run runs the command after flags parsed, it returns the process exit code.
+	run func() int
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		runCommand(),
+		dryRunCommand(),
+		serveCommand(),
+		validateCommand(),
+		versionCommand(),
+	}
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// execute parses flags of the command and runs it.
+func (c *command) execute(args []string) int {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	globals.register(fs)
+	if c.flags != nil {
+		c.flags(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitCode(err)
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Unexpected arguments %v for command '%s'\n", fs.Args(), c.name)
+		return ExitUsage
+	}
+
+	if err := globals.setup(); err != nil {
+		logrus.Error(err)
+		return ExitUsage
+	}
+
+	return c.run()
+}
+
+// loadConfig loads the config file and applies command line overrides.
+func loadConfig(o *overrides) error {
+	if err := config.Load(globals.configFile); err != nil {
+		return fmt.Errorf("load config failed: %v", err)
+	}
+	o.apply(&config.Config)
+
+	return policy.Validate(config.Config)
+}
+
+// newClient creates Harbor client and sets it as the global API client used by policy processors.
+func newClient(closing <-chan struct{}) (*harbor.Client, error) {
+	client, err := harbor.NewClient(&config.Config, closing)
+	if err != nil {
+		return nil, fmt.Errorf("init Harbor client error: %v", err)
+	}
+	harbor.APIClient = client
+
+	return client, nil
+}
+
+func runCommand() *command {
+	o := &overrides{}
+	opts := cleaner.Options{}
+	interactive := false
+	return &command{
+		name:  "run",
+		short: "Clean images once according to the policy",
+		flags: func(fs *flag.FlagSet) {
+			o.register(fs)
+			fs.StringVar(&opts.StateFile, "state", "/workspace/state.json", "File to persist progress of the clean, leave it empty to disable checkpointing")
+			fs.BoolVar(&opts.Resume, "resume", false, "Whether to resume an interrupted clean recorded in the state file")
+			fs.BoolVar(&interactive, "interactive", false, "Whether to confirm images to clean interactively before cleaning")
+		},
+		run: func() int {
+			if err := loadConfig(o); err != nil {
+				logrus.Error(err)
+				return ExitError
+			}
+
+			if interactive {
+				if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+					logrus.Error("Interactive mode requires stdin to be a terminal")
+					return ExitUsage
+				}
+				opts.Confirmer = cleaner.NewConfirmer(os.Stdin, os.Stdout)
+			}
+
+			client, err := newClient(withContext().Done())
+			if err != nil {
+				logrus.Error(err)
+				return ExitError
+			}
+
+			opts.Output = globals.output
+			return runClean(cleaner.NewRunner(client, config.Config, opts))
+		},
+	}
+}
+
+func dryRunCommand() *command {
+	o := &overrides{}
+	return &command{
+		name:  "dry-run",
+		short: "Show images that would be cleaned without cleaning them",
+		flags: o.register,
+		run: func() int {
+			if err := loadConfig(o); err != nil {
+				logrus.Error(err)
+				return ExitError
+			}
+
+			client, err := newClient(withContext().Done())
+			if err != nil {
+				logrus.Error(err)
+				return ExitError
+			}
+
+			runner := cleaner.NewRunner(client, config.Config, cleaner.Options{Output: globals.output})
+			if err := runner.DryRun(); err != nil {
+				logrus.Errorf("Dryrun error: %v", err)
+				return cleaner.ExitTotalFailure
+			}
+			return ExitSuccess
+		},
+	}
+}
+
+func serveCommand() *command {
+	o := &overrides{}
+	cron := ""
+	stateFile := ""
+	return &command{
+		name:  "serve",
+		short: "Run as a daemon to clean images by cron schedule",
+		flags: func(fs *flag.FlagSet) {
+			o.register(fs)
+			fs.StringVar(&cron, "cron", "", "Cron expression to schedule the clean, overrides 'trigger.cron'")
+			fs.StringVar(&stateFile, "state", "/workspace/state.json", "File to persist progress of the clean, leave it empty to disable checkpointing")
+		},
+		run: func() int {
+			if err := loadConfig(o); err != nil {
+				logrus.Error(err)
+				return ExitError
+			}
+			if len(cron) > 0 {
+				config.Config.Trigger = &config.Trigger{Cron: cron}
+				if err := config.Normalize(&config.Config); err != nil {
+					logrus.Errorf("Invalid cron expression '%s': %v", cron, err)
+					return ExitUsage
+				}
+			}
+			if !config.HasCronSchedule() {
+				logrus.Error("No cron schedule configured, set 'trigger.cron' or '--cron'")
+				return ExitUsage
+			}
+
+			ctx := withContext()
+			client, err := newClient(ctx.Done())
+			if err != nil {
+				logrus.Error(err)
+				return ExitError
+			}
+
+			scheduler := trigger.NewCronScheduler(config.Config.Trigger.Cron)
+			scheduler.Submit(func() {
+				runClean(cleaner.NewRunner(client, config.Config, cleaner.Options{
+					StateFile: stateFile,
+					Output:    globals.output,
+				}))
+			})
+			scheduler.Start()
+			<-ctx.Done()
+
+			return ExitSuccess
+		},
+	}
+}
+
+func validateCommand() *command {
+	o := &overrides{}
+	return &command{
+		name:  "validate",
+		short: "Validate the config file without accessing Harbor",
+		flags: o.register,
+		run: func() int {
+			if err := loadConfig(o); err != nil {
+				logrus.Errorf("Invalid config: %v", err)
+				return ExitError
+			}
+
+			fmt.Printf("Config %s is valid\n", globals.configFile)
+			return ExitSuccess
+		},
+	}
+}
+
+func versionCommand() *command {
+	return &command{
+		name:  "version",
+		short: "Print version of the cleaner",
+		run: func() int {
+			if globals.output == cleaner.OutputJSON {
+				b, _ := json.Marshal(map[string]string{
+					"version": version.VERSION,
+					"commit":  version.COMMIT,
+				})
+				fmt.Println(string(b))
+				return ExitSuccess
+			}
+
+			fmt.Printf("Version: %s\nCommit: %s\n", version.VERSION, version.COMMIT)
+			return ExitSuccess
+		},
+	}
+}
+
+// runClean runs the clean and reports the result, it returns the process exit code that reflects
+// the result.
+func runClean(runner cleaner.Runner) int {
+	result, err := runner.Clean()
+	if err != nil {
+		logrus.Errorf("Clean error: %v", err)
+		return cleaner.ExitTotalFailure
+	}
+	for _, e := range result.Errors {
+		logrus.Errorf("Clean error: %v", e)
+	}
+	logrus.Infof("Clean finished, %s", result.Summary())
+
+	if globals.output == cleaner.OutputJSON {
+		b, err := json.MarshalIndent(struct {
+			Status cleaner.Status `json:"status"`
+			*cleaner.Result
+		}{result.Status(), result}, "", "  ")
+		if err != nil {
+			logrus.Errorf("Marshal result error: %v", err)
+		} else {
+			fmt.Println(string(b))
+		}
+	}
+
+	return result.ExitCode()
+}
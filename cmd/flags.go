@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/cd1989/harbor-cleaner/pkg/cleaner"
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+)
+
+// globalFlags are flags shared by all commands, they can be given either before or after the command.
+type globalFlags struct {
+	configFile string
+	logLevel   string
+	output     string
+}
+
+var globals = &globalFlags{
+	configFile: "/workspace/config.yaml",
+	logLevel:   "info",
+	output:     cleaner.OutputText,
+}
+
+// register registers global flags to the flag set. Current values are used as defaults, so values
+// parsed before the command are kept when flags of the command are parsed.
+func (g *globalFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.configFile, "config", g.configFile, "Config file")
+	fs.StringVar(&g.logLevel, "log-level", g.logLevel, "Log level, e.g. debug, info, warning, error")
+	fs.StringVar(&g.output, "output", g.output, "Output format, 'text' or 'json'")
+}
+
+// setup configures logging according to the global flags.
+func (g *globalFlags) setup() error {
+	level, err := logrus.ParseLevel(g.logLevel)
+	if err != nil {
+		return fmt.Errorf("invalid log level '%s': %v", g.logLevel, err)
+	}
+	logrus.SetLevel(level)
+
+	switch g.output {
+	case cleaner.OutputText:
+		logrus.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	case cleaner.OutputJSON:
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return fmt.Errorf("invalid output format '%s', 'text' or 'json' expected", g.output)
+	}
+
+	return nil
+}
+
+// hasCronSchedule loads the config file and checks whether cron schedule configured.
+func (g *globalFlags) hasCronSchedule() bool {
+	if err := config.Load(g.configFile); err != nil {
+		return false
+	}
+	return config.HasCronSchedule()
+}
+
+// stringList is a flag that can be given multiple times or as comma separated values.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// overrides are flags to override common fields of the config file.
+type overrides struct {
+	projects   stringList
+	repos      stringList
+	policyType string
+	number     int
+}
+
+func (o *overrides) register(fs *flag.FlagSet) {
+	fs.Var(&o.projects, "project", "Projects to clean, glob patterns allowed, can be given multiple times or comma separated, overrides 'projects'")
+	fs.Var(&o.repos, "repo", "Repos to clean in form of 'project/repo', glob patterns allowed, can be given multiple times or comma separated, overrides 'repos'")
+	fs.StringVar(&o.policyType, "policy-type", "", "Policy type, overrides 'policy.type'")
+	fs.IntVar(&o.number, "number", -1, "Number of tags to retain for number policy, overrides 'policy.numberPolicy.number'")
+}
+
+// apply applies overrides given in command line to the config.
+func (o *overrides) apply(c *config.C) {
+	// Projects and repos together define the scope, so both are overridden if any is given.
+	if len(o.projects) > 0 || len(o.repos) > 0 {
+		c.Projects = o.projects
+		c.Repos = o.repos
+	}
+
+	if len(o.policyType) > 0 {
+		c.Policy.Type = o.policyType
+	}
+
+	if o.number >= 0 {
+		if c.Policy.NumPolicy == nil {
+			c.Policy.NumPolicy = &config.NumPolicy{}
+		}
+		c.Policy.NumPolicy.Num = o.number
+	}
+}
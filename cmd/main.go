@@ -3,70 +3,105 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/sirupsen/logrus"
 
-	"github.com/cd1989/harbor-cleaner/pkg/cleaner"
-	"github.com/cd1989/harbor-cleaner/pkg/config"
-	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+	_ "github.com/cd1989/harbor-cleaner/pkg/policy/age"
+	_ "github.com/cd1989/harbor-cleaner/pkg/policy/buckets"
+	_ "github.com/cd1989/harbor-cleaner/pkg/policy/composite"
+	_ "github.com/cd1989/harbor-cleaner/pkg/policy/expiry"
+	_ "github.com/cd1989/harbor-cleaner/pkg/policy/git"
 	_ "github.com/cd1989/harbor-cleaner/pkg/policy/number"
+	_ "github.com/cd1989/harbor-cleaner/pkg/policy/popularity"
+	_ "github.com/cd1989/harbor-cleaner/pkg/policy/pullrequest"
+	_ "github.com/cd1989/harbor-cleaner/pkg/policy/quota"
 	_ "github.com/cd1989/harbor-cleaner/pkg/policy/regex"
+	_ "github.com/cd1989/harbor-cleaner/pkg/policy/semver"
+	_ "github.com/cd1989/harbor-cleaner/pkg/policy/size"
 	_ "github.com/cd1989/harbor-cleaner/pkg/policy/touch"
-	"github.com/cd1989/harbor-cleaner/pkg/trigger"
 )
 
-var configFile *string
-var dryRun *bool
-
 func main() {
-	logrus.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	os.Exit(Execute(os.Args[1:]))
+}
 
-	configFile = flag.String("config", "/workspace/config.yaml", "Config file")
-	dryRun = flag.Bool("dryrun", false, "Whether only dry run the clean")
-	if !flag.Parsed() {
-		flag.Parse()
+// Execute parses global flags and runs the command given in args, it returns the process exit code.
+// If no command given, it falls back to 'serve' when cron schedule configured, otherwise 'run'.
+func Execute(args []string) int {
+	fs := flag.NewFlagSet("cleaner", flag.ContinueOnError)
+	fs.Usage = func() {
+		usage(fs)
+	}
+	globals.register(fs)
+	// Deprecated: use 'dry-run' command instead.
+	dryRun := fs.Bool("dryrun", false, "Deprecated, use 'dry-run' command instead")
+	if err := fs.Parse(args); err != nil {
+		return exitCode(err)
 	}
 
-	err := config.Load(*configFile)
-	if err != nil {
-		logrus.Fatalf("Load config failed: %v", err)
+	args = fs.Args()
+	name := ""
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	gracefulShutdown(cancel)
-	client, err := harbor.NewClient(&config.Config, ctx.Done())
-	if err != nil {
-		logrus.Fatalf("Init Harbor client error: %v", err)
+	if name == "" {
+		if err := globals.setup(); err != nil {
+			logrus.Error(err)
+			return ExitError
+		}
+
+		if *dryRun {
+			logrus.Warning("Flag '--dryrun' is deprecated, use 'dry-run' command instead")
+			name = "dry-run"
+		} else if globals.hasCronSchedule() {
+			name = "serve"
+		} else {
+			name = "run"
+		}
+	}
+
+	if name == "help" {
+		fs.Usage()
+		return ExitSuccess
 	}
-	harbor.APIClient = client
 
-	if config.HasCronSchedule() {
-		scheduler := trigger.NewCronScheduler(config.Config.Trigger.Cron)
-		scheduler.Submit(func() {
-			RunTask(client)
-		})
-		scheduler.Start()
-		<-ctx.Done()
-	} else {
-		RunTask(client)
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Unknown command '%s'\n\n", name)
+		fs.Usage()
+		return ExitUsage
 	}
+
+	return cmd.execute(args)
 }
 
-// RunTask starts cleanup task
-func RunTask(client *harbor.Client) {
-	runner := cleaner.NewRunner(client, config.Config)
-	if *dryRun {
-		if err := runner.DryRun(); err != nil {
-			logrus.Errorf("Dryrun error: %v", err)
-		}
-	} else {
-		if err := runner.Clean(); err != nil {
-			logrus.Errorf("Clean error: %v", err)
-		}
+func usage(fs *flag.FlagSet) {
+	fmt.Fprintf(os.Stderr, "Usage: %s [global flags] <command> [flags]\n\nCommands:\n", fs.Name())
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.short)
+	}
+	fmt.Fprintf(os.Stderr, "\nGlobal flags:\n")
+	fs.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for flags of a command.\n", fs.Name())
+}
+
+func exitCode(err error) int {
+	if err == flag.ErrHelp {
+		return ExitSuccess
 	}
+	return ExitUsage
+}
+
+// withContext creates a context that will be cancelled when system signals caught.
+func withContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	gracefulShutdown(cancel)
+	return ctx
 }
 
 // gracefulShutdown catches signals of Interrupt, SIGINT, SIGTERM, SIGQUIT and cancel a context.
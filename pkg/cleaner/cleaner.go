@@ -1,98 +1,456 @@
 package cleaner
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"sort"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/cd1989/harbor-cleaner/pkg/config"
 	"github.com/cd1989/harbor-cleaner/pkg/harbor"
 	"github.com/cd1989/harbor-cleaner/pkg/policy"
+	"github.com/cd1989/harbor-cleaner/pkg/protect"
+)
+
+// Output formats of the run result.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
 )
 
 type Runner interface {
 	DryRun() error
-	Clean() error
+	Clean() (*Result, error)
+}
+
+// Options configures how a runner runs.
+type Options struct {
+	// StateFile is the file to persist progress of a clean run, checkpointing is disabled if empty.
+	StateFile string
+	// Resume indicates to continue an interrupted run recorded in StateFile.
+	Resume bool
+	// Confirmer asks for confirmation of candidates before cleaning, nil means no confirmation needed.
+	Confirmer *Confirmer
+	// Output is format of the dry run output, 'text' or 'json', defaults to 'text'.
+	Output string
+}
+
+// DryRunOutput is output of dry run in json format.
+type DryRunOutput struct {
+	Repos      int                 `json:"repos"`
+	Images     int                 `json:"images"`
+	Aborted    string              `json:"aborted,omitempty"`
+	Candidates []*policy.Candidate `json:"candidates"`
+	// Excluded are tags excluded from candidates as they're referenced by protection sources
+	Excluded []*protect.Exclusion `json:"excluded,omitempty"`
 }
 
 type runner struct {
 	client *harbor.Client
 	cfg    config.C
+	opts   Options
 }
 
-func NewRunner(client *harbor.Client, cfg config.C) Runner {
+func NewRunner(client *harbor.Client, cfg config.C, opts Options) Runner {
 	return &runner{
 		client: client,
 		cfg:    cfg,
+		opts:   opts,
 	}
 }
 
 func (c *runner) DryRun() error {
-	factory := policy.GetProcessorFactory((policy.Type)(c.cfg.Policy.Type))
-	if factory == nil {
-		return fmt.Errorf("no processor factory found for policy type: %s", c.cfg.Policy.Type)
+	candidates, excluded, err := c.listCandidates()
+	if err != nil {
+		return err
 	}
 
-	candidates, err := factory(c.cfg).ListCandidates()
-	if err != nil {
-		return fmt.Errorf("list candidates error: %v", err)
+	if c.opts.Output == OutputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(&DryRunOutput{
+			Repos:      len(candidates),
+			Images:     countTags(candidates),
+			Aborted:    c.checkSafety(candidates),
+			Candidates: candidates,
+			Excluded:   excluded,
+		})
+	}
+
+	for _, e := range excluded {
+		fmt.Printf("Repo: %s/%s, tag: %s excluded, referenced by %s\n", e.Project, e.Repo, e.Tag, e.Source)
 	}
 
 	imageCount := 0
 	for _, repo := range candidates {
 		for _, tag := range repo.Tags {
 			imageCount++
-			fmt.Printf("[%s] %s/%s:%s\n", tag.Created.Format("2006-01-02 15:04:05"), repo.Project, repo.Repo, tag.Name)
+			fmt.Printf("[%s] %s/%s:%s%s\n", tag.Created.Format("2006-01-02 15:04:05"), repo.Project, repo.Repo, tag.Name, metadata(tag))
 		}
 		for _, tags := range repo.Protected {
 			fmt.Printf("Repo: %s/%s, tags: %v to protect\n", repo.Project, repo.Repo, tags)
 		}
+		if len(c.cfg.ScopedPolicies) > 0 {
+			fmt.Printf("Repo: %s/%s, policy scope: %s\n", repo.Project, repo.Repo, repo.Scope)
+		}
+		fmt.Printf("Repo: %s/%s, %d distinct images remain\n", repo.Project, repo.Repo, repo.Remains)
 	}
 	fmt.Printf("Total %d repos with %d images are ready for clean\n", len(candidates), imageCount)
+	if reason := c.checkSafety(candidates); len(reason) > 0 {
+		fmt.Printf("Warning: clean would be aborted, %s\n", reason)
+	}
 
 	return nil
 }
 
-func (c *runner) Clean() error {
-	factory := policy.GetProcessorFactory((policy.Type)(c.cfg.Policy.Type))
-	if factory == nil {
-		return fmt.Errorf("no processor factory found for policy type: %s", c.cfg.Policy.Type)
+func (c *runner) Clean() (*Result, error) {
+	state, err := c.prepareState()
+	if err != nil {
+		return nil, err
 	}
 
-	candidates, err := factory(c.cfg).ListCandidates()
-	if err != nil {
-		return fmt.Errorf("list candidates error: %v", err)
+	// Push back tags protected by the interrupted run before anything else
+	result := &Result{}
+	c.restoreInterrupted(state, result)
+
+	// Images may be deployed since the interrupted run, exclude them from the saved plan
+	if c.opts.Resume {
+		if err := c.protectPending(state); err != nil {
+			return nil, err
+		}
+	}
+
+	candidates := state.Pending()
+	if c.opts.Confirmer != nil {
+		confirmed, err := c.opts.Confirmer.Confirm(candidates)
+		if err != nil {
+			return nil, err
+		}
+		// Operator quit or confirmed nothing, keep the state as it is so that the run can be resumed
+		if len(confirmed) == 0 {
+			logrus.Info("Nothing confirmed, clean aborted")
+			result.Aborted = true
+			result.Reason = "nothing confirmed by operator"
+			return result, nil
+		}
+		state.Plan = confirmedPlan(state, confirmed)
+		c.saveState(state)
+		candidates = confirmed
 	}
 
 	// Clean the collected images
+	if reason := c.checkSafety(candidates); len(reason) > 0 {
+		logrus.Errorf("Clean aborted: %s", reason)
+		result.Aborted = true
+		result.Reason = reason
+		return result, nil
+	}
+
 	logrus.Infof("Start to clean images for %d repo...", len(candidates))
-	count := 0
 	for _, repo := range candidates {
+		// Protected manifests would be overwritten, the repo is retried when tags restored
+		if _, ok := state.Protected[repoKey(repo)]; ok {
+			logrus.Errorf("Tags of %s protected by the interrupted run not restored, skip this repo", repoKey(repo))
+			result.Skipped += len(repo.Tags)
+			continue
+		}
+
 		repoCleaner := NewRepoCleaner(repo, c.client)
 
 		// Protect tags not to be deleted as side effect of other tags' deletion
 		logrus.Infof("Start to protect tags")
 		if err := repoCleaner.Protect(); err != nil {
 			logrus.Error("Failed to protect tags, skip this repo")
+			result.Skipped += len(repo.Tags)
+			result.Errors = append(result.Errors, ItemError{
+				Project: repo.Project,
+				Repo:    repo.Repo,
+				Op:      OpProtect,
+				Err:     err.Error(),
+			})
+			continue
+		}
+		result.Protected += repoCleaner.ProtectedCount()
+
+		// Persist manifests of protected tags before deleting, so they can be restored on resume
+		state.SetProtected(repo, repoCleaner.Protected())
+		if err := c.persistState(state); err != nil {
+			logrus.Errorf("Save protected tags to state error: %v, skip this repo", err)
+			state.SetProtected(repo, nil)
+			result.Skipped += len(repo.Tags)
+			result.Errors = append(result.Errors, ItemError{
+				Project: repo.Project,
+				Repo:    repo.Repo,
+				Op:      OpProtect,
+				Err:     err.Error(),
+			})
 			continue
 		}
 
 		// Delete tags
 		logrus.Infof("Start to clean %d images for repo '%s'...", len(repo.Tags), repo.Repo)
-		n, err := repoCleaner.Clean()
+		n := repoCleaner.Clean()
+		result.Deleted += n
+		result.Failed += len(repoCleaner.Failed())
+
+		// Push back tags that are removed as side effect of previous tag deletion
+		n, err := repoCleaner.Restore()
+		result.Restored += n
+		result.Errors = append(result.Errors, repoCleaner.Errors()...)
 		if err != nil {
-			logrus.Warningf("Clean tags error: %v", err)
+			logrus.Errorf("Restore tags error: %v", err)
+		}
+
+		// Tags failed to push back are kept in the state and retried on resume, the run is not
+		// finished until they're restored.
+		state.SetProtected(repo, repoCleaner.Unrestored())
+		state.MarkDone(repo, repoCleaner.Failed())
+		c.saveState(state)
+	}
+	logrus.Infof("Totally %d images cleaned", result.Deleted)
+
+	state.Finished = len(state.Pending()) == 0 && len(state.Protected) == 0
+	c.saveState(state)
+
+	return result, nil
+}
+
+// restoreInterrupted pushes back protected tags recorded in the state, they're left by a run
+// interrupted between deleting tags and restoring them, or failed to push back in a previous run.
+// Tags failed to restore are recorded as errors and kept in the state.
+func (c *runner) restoreInterrupted(state *State, result *Result) {
+	var keys []string
+	for key := range state.Protected {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		repo := state.Find(key)
+		if repo == nil {
+			logrus.Warningf("Repo %s with protected tags not found in the plan, ignore it", key)
+			delete(state.Protected, key)
 			continue
 		}
-		count += n
 
-		// Push back tags that are removed as side effect of previous tag deletion
-		if err := repoCleaner.Restore(); err != nil {
-			logrus.Errorf("Restore tags error: %v", err)
+		logrus.Infof("Start to restore tags of %s protected by the interrupted run", key)
+		repoCleaner := NewRepoCleaner(repo, c.client)
+		if err := repoCleaner.LoadProtected(state.Protected[key]); err != nil {
+			logrus.Errorf("Create repo client for repo %s error: %v", key, err)
+			result.Errors = append(result.Errors, ItemError{
+				Project: repo.Project,
+				Repo:    repo.Repo,
+				Op:      OpRestore,
+				Err:     err.Error(),
+			})
 			continue
 		}
+
+		n, err := repoCleaner.Restore()
+		result.Restored += n
+		result.Errors = append(result.Errors, repoCleaner.Errors()...)
+		if err != nil {
+			logrus.Errorf("Restore tags error: %v", err)
+		}
+		state.SetProtected(repo, repoCleaner.Unrestored())
+		c.saveState(state)
+	}
+}
+
+// listCandidates lists candidates by the policies, tags referenced by protection sources are excluded.
+func (c *runner) listCandidates() ([]*policy.Candidate, []*protect.Exclusion, error) {
+	sources, err := protect.NewSources(c.cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create protection sources error: %v", err)
+	}
+
+	candidates, err := policy.ListCandidates(c.cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list candidates error: %v", err)
+	}
+
+	return c.protect(sources, candidates)
+}
+
+// protect excludes tags referenced by the protection sources from the candidates.
+func (c *runner) protect(sources []protect.Source, candidates []*policy.Candidate) ([]*policy.Candidate, []*protect.Exclusion, error) {
+	if len(sources) == 0 {
+		return candidates, nil, nil
+	}
+
+	refs, err := protect.Collect(sources)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	candidates, excluded := protect.Apply(candidates, refs)
+	return candidates, excluded, nil
+}
+
+// protectPending excludes tags referenced by protection sources from repos not handled yet in the
+// plan. It's done again on resume, since images may be deployed after the interrupted run. Repos
+// with protected tags to restore are kept as they are, they're skipped until restored.
+func (c *runner) protectPending(state *State) error {
+	sources, err := protect.NewSources(c.cfg)
+	if err != nil {
+		return fmt.Errorf("create protection sources error: %v", err)
+	}
+	if len(sources) == 0 {
+		return nil
+	}
+
+	var pending []*policy.Candidate
+	for _, r := range state.Pending() {
+		if _, ok := state.Protected[repoKey(r)]; !ok {
+			pending = append(pending, r)
+		}
+	}
+
+	candidates, excluded, err := c.protect(sources, pending)
+	if err != nil {
+		return err
+	}
+	for _, e := range excluded {
+		logrus.Infof("Tag %s/%s:%s excluded, referenced by %s", e.Project, e.Repo, e.Tag, e.Source)
+	}
+
+	remains := make(map[string]bool)
+	for _, r := range candidates {
+		remains[repoKey(r)] = true
+	}
+
+	var plan []*policy.Candidate
+	for _, r := range state.Plan {
+		key := repoKey(r)
+		_, protected := state.Protected[key]
+		if state.Done[key] || protected || remains[key] {
+			plan = append(plan, r)
+		}
 	}
-	logrus.Infof("Totally %d images cleaned", count)
+	state.Plan = plan
+	c.saveState(state)
 
 	return nil
 }
+
+// checkSafety checks candidates against safety limits, it returns reason to abort the run, or
+// empty string if all limits are satisfied.
+func (c *runner) checkSafety(candidates []*policy.Candidate) string {
+	if c.cfg.Safety.MaxDeletions <= 0 {
+		return ""
+	}
+
+	count := countTags(candidates)
+	if count > c.cfg.Safety.MaxDeletions {
+		return fmt.Sprintf("%d images to clean exceeds safety limit %d", count, c.cfg.Safety.MaxDeletions)
+	}
+
+	return ""
+}
+
+// confirmedPlan gets plan with repos not confirmed removed, repos already handled and repos with
+// protected tags to restore are kept.
+func confirmedPlan(state *State, confirmed []*policy.Candidate) []*policy.Candidate {
+	keep := make(map[string]bool)
+	for _, r := range confirmed {
+		keep[repoKey(r)] = true
+	}
+
+	var plan []*policy.Candidate
+	for _, r := range state.Plan {
+		_, protected := state.Protected[repoKey(r)]
+		if state.Done[repoKey(r)] || keep[repoKey(r)] || protected {
+			plan = append(plan, r)
+		}
+	}
+
+	return plan
+}
+
+// prepareState gets the state to run with. When resuming, state is loaded from the state file and
+// candidates are not recomputed (protection sources are applied to them again in Clean), otherwise
+// candidates are computed by the policy processor and a new state is created.
+func (c *runner) prepareState() (*State, error) {
+	if c.opts.Resume {
+		if len(c.opts.StateFile) == 0 {
+			return nil, fmt.Errorf("state file not specified, can't resume")
+		}
+
+		state, err := LoadState(c.opts.StateFile)
+		if err != nil {
+			return nil, fmt.Errorf("load state error: %v", err)
+		}
+		if state.Finished {
+			return nil, fmt.Errorf("run recorded in %s already finished, nothing to resume", c.opts.StateFile)
+		}
+		if state.Policy != c.cfg.Policy.Type {
+			logrus.Warningf("Policy type changed from '%s' to '%s' since the interrupted run, continue with the original plan", state.Policy, c.cfg.Policy.Type)
+		}
+		logrus.Infof("Resume run started at %s, %d of %d repos done", state.StartTime.Format("2006-01-02 15:04:05"), len(state.Done), len(state.Plan))
+
+		return state, nil
+	}
+
+	if len(c.opts.StateFile) > 0 {
+		if state, err := LoadState(c.opts.StateFile); err == nil && !state.Finished {
+			logrus.Warningf("Found unfinished run started at %s in %s, it will be overwritten, use resume to continue it", state.StartTime.Format("2006-01-02 15:04:05"), c.opts.StateFile)
+		}
+	}
+
+	candidates, excluded, err := c.listCandidates()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range excluded {
+		logrus.Infof("Tag %s/%s:%s excluded, referenced by %s", e.Project, e.Repo, e.Tag, e.Source)
+	}
+
+	state := NewState(c.cfg.Policy.Type, candidates)
+	c.saveState(state)
+
+	return state, nil
+}
+
+// persistState persists the state if checkpointing enabled, error is returned if failed.
+func (c *runner) persistState(state *State) error {
+	if len(c.opts.StateFile) == 0 {
+		return nil
+	}
+
+	return state.Save(c.opts.StateFile)
+}
+
+// saveState persists the state if checkpointing enabled. Failure to save state is logged but not
+// regarded as an error of the run.
+func (c *runner) saveState(state *State) {
+	if len(c.opts.StateFile) == 0 {
+		return
+	}
+
+	if err := state.Save(c.opts.StateFile); err != nil {
+		logrus.Errorf("Save state to %s error: %v", c.opts.StateFile, err)
+	}
+}
+
+// metadata formats platform, author and docker version of the image shown in dry run, fields unknown
+// are omitted.
+func metadata(t policy.Tag) string {
+	var fields []string
+	if len(t.OS) > 0 || len(t.Architecture) > 0 {
+		fields = append(fields, fmt.Sprintf("platform: %s/%s", t.OS, t.Architecture))
+	}
+	if len(t.Author) > 0 {
+		fields = append(fields, fmt.Sprintf("author: %s", t.Author))
+	}
+	if len(t.DockerVersion) > 0 {
+		fields = append(fields, fmt.Sprintf("docker: %s", t.DockerVersion))
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return " (" + strings.Join(fields, ", ") + ")"
+}
@@ -0,0 +1,190 @@
+package cleaner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+// largestReposToShow is number of repos with most images to clean shown in the summary.
+const largestReposToShow = 5
+
+// Confirmer asks operator to confirm candidates before cleaning them. It shows a summary of the
+// candidates and reads decisions from 'in', only confirmed candidates are returned.
+type Confirmer struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewConfirmer creates a confirmer that reads from 'in' and writes prompts to 'out'.
+func NewConfirmer(in io.Reader, out io.Writer) *Confirmer {
+	return &Confirmer{
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+}
+
+// Confirm shows summary of the candidates and asks for confirmation. Operator can confirm all,
+// confirm per project or per repo, exclude repos by 'project/repo' pattern ('*' and '?' supported),
+// or quit. Nil is returned if nothing confirmed.
+func (c *Confirmer) Confirm(candidates []*policy.Candidate) ([]*policy.Candidate, error) {
+	remains := candidates
+	for {
+		if len(remains) == 0 {
+			fmt.Fprintln(c.out, "Nothing to clean")
+			return nil, nil
+		}
+		c.summary(remains)
+
+		answer, err := c.ask("Proceed? [a]ll, per [p]roject, per [r]epo, [e]xclude <project/repo>..., [q]uit: ")
+		if err != nil {
+			return nil, err
+		}
+
+		fields := strings.Fields(answer)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "a", "all":
+			return remains, nil
+		case "p", "project":
+			return c.perProject(remains)
+		case "r", "repo":
+			return c.perRepo(remains)
+		case "e", "exclude":
+			remains = exclude(remains, fields[1:])
+		case "q", "quit":
+			return nil, nil
+		default:
+			fmt.Fprintf(c.out, "Unrecognized answer '%s'\n", answer)
+		}
+	}
+}
+
+// summary prints number of repos and images to clean per project, and repos with most images.
+func (c *Confirmer) summary(candidates []*policy.Candidate) {
+	projects, byProject := groupByProject(candidates)
+
+	total := 0
+	fmt.Fprintln(c.out, "Images to clean per project:")
+	for _, p := range projects {
+		count := countTags(byProject[p])
+		total += count
+		fmt.Fprintf(c.out, "  %-30s %5d repos %7d images\n", p, len(byProject[p]), count)
+	}
+	fmt.Fprintf(c.out, "Total %d repos with %d images\n", len(candidates), total)
+
+	sorted := make([]*policy.Candidate, len(candidates))
+	copy(sorted, candidates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].Tags) > len(sorted[j].Tags)
+	})
+	if len(sorted) > largestReposToShow {
+		sorted = sorted[:largestReposToShow]
+	}
+	fmt.Fprintln(c.out, "Largest repos affected:")
+	for _, r := range sorted {
+		fmt.Fprintf(c.out, "  %-50s %7d images\n", repoKey(r), len(r.Tags))
+	}
+}
+
+func (c *Confirmer) perProject(candidates []*policy.Candidate) ([]*policy.Candidate, error) {
+	var confirmed []*policy.Candidate
+	projects, byProject := groupByProject(candidates)
+	for _, p := range projects {
+		repos := byProject[p]
+		for {
+			answer, err := c.ask(fmt.Sprintf("Clean %d images in %d repos of project '%s'? [y]es, [n]o, [r]eview repos: ", countTags(repos), len(repos), p))
+			if err != nil {
+				return nil, err
+			}
+
+			if answer == "y" || answer == "yes" {
+				confirmed = append(confirmed, repos...)
+			} else if answer == "r" || answer == "review" {
+				reviewed, err := c.perRepo(repos)
+				if err != nil {
+					return nil, err
+				}
+				confirmed = append(confirmed, reviewed...)
+			} else if answer != "n" && answer != "no" {
+				continue
+			}
+			break
+		}
+	}
+
+	return confirmed, nil
+}
+
+func (c *Confirmer) perRepo(candidates []*policy.Candidate) ([]*policy.Candidate, error) {
+	var confirmed []*policy.Candidate
+	for _, r := range candidates {
+		for {
+			answer, err := c.ask(fmt.Sprintf("Clean %d images in repo '%s'? [y]es, [n]o: ", len(r.Tags), repoKey(r)))
+			if err != nil {
+				return nil, err
+			}
+
+			if answer == "y" || answer == "yes" {
+				confirmed = append(confirmed, r)
+			} else if answer != "n" && answer != "no" {
+				continue
+			}
+			break
+		}
+	}
+
+	return confirmed, nil
+}
+
+// ask prints the prompt and reads a trimmed line of answer. Reaching end of input is an error,
+// so that a closed input never results in confirmation.
+func (c *Confirmer) ask(prompt string) (string, error) {
+	fmt.Fprint(c.out, prompt)
+	line, err := c.in.ReadString('\n')
+	if err != nil && (err != io.EOF || len(line) == 0) {
+		return "", fmt.Errorf("read answer error: %v", err)
+	}
+
+	return strings.ToLower(strings.TrimSpace(line)), nil
+}
+
+// exclude removes candidates whose 'project/repo' matches any of the patterns, patterns are matched
+// the same way as scope patterns in the config, '*' matches '/' as well.
+func exclude(candidates []*policy.Candidate, patterns []string) []*policy.Candidate {
+	var remains []*policy.Candidate
+	for _, r := range candidates {
+		if !policy.MatchAny(patterns, repoKey(r)) {
+			remains = append(remains, r)
+		}
+	}
+
+	return remains
+}
+
+func groupByProject(candidates []*policy.Candidate) ([]string, map[string][]*policy.Candidate) {
+	var projects []string
+	byProject := make(map[string][]*policy.Candidate)
+	for _, r := range candidates {
+		if _, ok := byProject[r.Project]; !ok {
+			projects = append(projects, r.Project)
+		}
+		byProject[r.Project] = append(byProject[r.Project], r)
+	}
+
+	return projects, byProject
+}
+
+func countTags(candidates []*policy.Candidate) int {
+	count := 0
+	for _, r := range candidates {
+		count += len(r.Tags)
+	}
+	return count
+}
@@ -0,0 +1,56 @@
+package cleaner
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+func testCandidates() []*policy.Candidate {
+	return []*policy.Candidate{
+		{Project: "library", Repo: "busybox", Tags: []policy.Tag{{Name: "1.0"}}},
+		{Project: "library", Repo: "alpine", Tags: []policy.Tag{{Name: "3.8"}, {Name: "3.9"}}},
+		{Project: "ci", Repo: "builder", Tags: []policy.Tag{{Name: "v1"}}},
+		{Project: "ci", Repo: "runner", Tags: []policy.Tag{{Name: "v2"}}},
+	}
+}
+
+func repos(candidates []*policy.Candidate) []string {
+	var keys []string
+	for _, c := range candidates {
+		keys = append(keys, repoKey(c))
+	}
+	return keys
+}
+
+func TestConfirm(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected []string
+	}{
+		{"a\n", []string{"library/busybox", "library/alpine", "ci/builder", "ci/runner"}},
+		{"q\n", nil},
+		{"e ci/*\na\n", []string{"library/busybox", "library/alpine"}},
+		{"e *busybox ci/run*\na\n", []string{"library/alpine", "ci/builder"}},
+		{"p\ny\nn\n", []string{"library/busybox", "library/alpine"}},
+		{"p\nr\nn\ny\ny\n", []string{"library/alpine", "ci/builder", "ci/runner"}},
+		{"r\ny\nn\nx\nn\ny\n", []string{"library/busybox", "ci/runner"}},
+	}
+
+	for _, c := range cases {
+		confirmer := NewConfirmer(strings.NewReader(c.input), &bytes.Buffer{})
+		confirmed, err := confirmer.Confirm(testCandidates())
+		assert.Nil(t, err)
+		assert.Equal(t, c.expected, repos(confirmed))
+	}
+}
+
+func TestConfirmEOF(t *testing.T) {
+	confirmer := NewConfirmer(strings.NewReader(""), &bytes.Buffer{})
+	_, err := confirmer.Confirm(testCandidates())
+	assert.NotNil(t, err)
+}
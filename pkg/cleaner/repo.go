@@ -18,13 +18,17 @@ type RepoCleaner struct {
 	candidate  *policy.Candidate
 	client     *harbor.Client
 	repoClient *harbor.RepoClient
-	protected  []protectedTagsMenifest
+	protected  []*ProtectedManifest
+	errors     []ItemError
 }
 
-type protectedTagsMenifest struct {
-	tags      []string
-	mediaType string
-	payload   []byte
+// ProtectedManifest is manifest of protected tags pulled before deleting, it's used to push back
+// the tags after deleting. It's persisted in the state, so that tags can be restored on resume if
+// the run is interrupted.
+type ProtectedManifest struct {
+	Tags      []string `json:"tags"`
+	MediaType string   `json:"mediaType"`
+	Payload   []byte   `json:"payload"`
 }
 
 func NewRepoCleaner(candidate *policy.Candidate, client *harbor.Client) *RepoCleaner {
@@ -36,15 +40,13 @@ func NewRepoCleaner(candidate *policy.Candidate, client *harbor.Client) *RepoCle
 
 func (c *RepoCleaner) Protect() error {
 	if len(c.candidate.Protected) > 0 {
-		repoClient, err := harbor.NewRepoClient(c.client, fmt.Sprintf("%s/%s", c.candidate.Project, c.candidate.Repo))
-		if err != nil {
+		if err := c.connect(); err != nil {
 			logrus.Errorf("Create repo client for repo %s/%s error: %v, skip this repo", c.candidate.Project, c.candidate.Repo, err)
 			return err
 		}
-		c.repoClient = repoClient
 
 		for digestID, tags := range c.candidate.Protected {
-			_, mediaType, payload, err := repoClient.PullManifest(digestID, acceptMediaTypes)
+			_, mediaType, payload, err := c.repoClient.PullManifest(digestID, acceptMediaTypes)
 			if err != nil {
 				logrus.Errorf("Pulling manifest %s/%s:%s error: %v", c.candidate.Project, c.candidate.Repo, digestID, err)
 				return err
@@ -53,10 +55,10 @@ func (c *RepoCleaner) Protect() error {
 				mediaType = schema1.MediaTypeManifest
 			}
 
-			c.protected = append(c.protected, protectedTagsMenifest{
-				tags:      tags,
-				mediaType: mediaType,
-				payload:   payload,
+			c.protected = append(c.protected, &ProtectedManifest{
+				Tags:      tags,
+				MediaType: mediaType,
+				Payload:   payload,
 			})
 		}
 	}
@@ -64,30 +66,114 @@ func (c *RepoCleaner) Protect() error {
 	return nil
 }
 
-func (c *RepoCleaner) Clean() (int, error) {
+// Protected returns manifests of protected tags pulled in Protect.
+func (c *RepoCleaner) Protected() []*ProtectedManifest {
+	return c.protected
+}
+
+// LoadProtected loads manifests of protected tags saved by an interrupted run, so that they can be
+// pushed back by Restore.
+func (c *RepoCleaner) LoadProtected(manifests []*ProtectedManifest) error {
+	if err := c.connect(); err != nil {
+		return err
+	}
+	c.protected = manifests
+
+	return nil
+}
+
+func (c *RepoCleaner) connect() error {
+	repoClient, err := harbor.NewRepoClient(c.client, fmt.Sprintf("%s/%s", c.candidate.Project, c.candidate.Repo))
+	if err != nil {
+		return err
+	}
+	c.repoClient = repoClient
+
+	return nil
+}
+
+// Clean deletes tags of the candidate, it returns number of tags deleted. Tags failed to delete are
+// recorded in errors, so that protected tags are always restored after it.
+func (c *RepoCleaner) Clean() int {
 	count := 0
 	for _, tag := range c.candidate.Tags {
 		if err := c.client.DeleteTag(c.candidate.Project, c.candidate.Repo, tag.Name); err != nil {
-			logrus.Warningf("Clean image '%s' error: %v", fmt.Sprintf("%s/%s:%s", c.candidate.Project, c.candidate.Repo, tag), err)
+			logrus.Warningf("Clean image '%s' error: %v", fmt.Sprintf("%s/%s:%s", c.candidate.Project, c.candidate.Repo, tag.Name), err)
+			c.addError(tag.Name, OpDelete, err)
 		} else {
 			count++
 		}
 	}
 
-	return count, nil
+	return count
+}
+
+// Failed returns tags that failed to be deleted in Clean.
+func (c *RepoCleaner) Failed() []string {
+	var failed []string
+	for _, e := range c.errors {
+		if e.Op == OpDelete {
+			failed = append(failed, e.Tag)
+		}
+	}
+	return failed
 }
 
-func (c *RepoCleaner) Restore() error {
+// Errors returns all errors happened when cleaning the repo.
+func (c *RepoCleaner) Errors() []ItemError {
+	return c.errors
+}
+
+// ProtectedCount returns number of tags protected.
+func (c *RepoCleaner) ProtectedCount() int {
+	count := 0
+	for _, tags := range c.candidate.Protected {
+		count += len(tags)
+	}
+	return count
+}
+
+// Restore pushes back all protected tags, it returns number of tags restored. It tries to push back
+// all tags even if some of them failed, and returns the last error if any. Tags failed to push back
+// are kept in Unrestored.
+func (c *RepoCleaner) Restore() (int, error) {
+	var lastErr error
+	var unrestored []*ProtectedManifest
+	count := 0
 	for _, r := range c.protected {
-		logrus.Infof("Start to push back tags %v to %s/%s", r.tags, c.candidate.Project, c.candidate.Repo)
-		for _, t := range r.tags {
-			_, err := c.repoClient.PushManifest(t, r.mediaType, r.payload)
+		logrus.Infof("Start to push back tags %v to %s/%s", r.Tags, c.candidate.Project, c.candidate.Repo)
+		var failed []string
+		for _, t := range r.Tags {
+			_, err := c.repoClient.PushManifest(t, r.MediaType, r.Payload)
 			if err != nil {
-				logrus.Errorf("Push manifest %s/%s:%s error: %v", c.candidate.Project, c.candidate.Repo, t)
-				return err
+				logrus.Errorf("Push manifest %s/%s:%s error: %v", c.candidate.Project, c.candidate.Repo, t, err)
+				c.addError(t, OpRestore, err)
+				failed = append(failed, t)
+				lastErr = err
+				continue
 			}
+			count++
+		}
+		if len(failed) > 0 {
+			unrestored = append(unrestored, &ProtectedManifest{Tags: failed, MediaType: r.MediaType, Payload: r.Payload})
 		}
 	}
+	c.protected = unrestored
 
-	return nil
+	return count, lastErr
+}
+
+// Unrestored returns manifests of protected tags not pushed back yet.
+func (c *RepoCleaner) Unrestored() []*ProtectedManifest {
+	return c.protected
+}
+
+func (c *RepoCleaner) addError(tag, op string, err error) {
+	c.errors = append(c.errors, ItemError{
+		Project: c.candidate.Project,
+		Repo:    c.candidate.Repo,
+		Tag:     tag,
+		Op:      op,
+		Err:     err.Error(),
+	})
 }
@@ -0,0 +1,105 @@
+package cleaner
+
+import (
+	"fmt"
+)
+
+// Process exit codes to report status of a clean run.
+const (
+	ExitSuccess        = 0
+	ExitPartialFailure = 2
+	ExitTotalFailure   = 3
+	ExitAborted        = 4
+)
+
+// Operations an item error can happen in.
+const (
+	OpProtect = "protect"
+	OpDelete  = "delete"
+	OpRestore = "restore"
+)
+
+// Status is the overall status of a clean run.
+type Status string
+
+const (
+	StatusSuccess        Status = "success"
+	StatusPartialFailure Status = "partialFailure"
+	StatusTotalFailure   Status = "totalFailure"
+	StatusAborted        Status = "aborted"
+)
+
+// ItemError describes an error happened on a repo or a tag. 'Tag' is empty if the error is
+// about the whole repo, for example, failed to protect tags of the repo.
+type ItemError struct {
+	Project string `json:"project"`
+	Repo    string `json:"repo"`
+	Tag     string `json:"tag,omitempty"`
+	Op      string `json:"op"`
+	Err     string `json:"error"`
+}
+
+func (e ItemError) Error() string {
+	if len(e.Tag) == 0 {
+		return fmt.Sprintf("%s %s/%s: %s", e.Op, e.Project, e.Repo, e.Err)
+	}
+	return fmt.Sprintf("%s %s/%s:%s: %s", e.Op, e.Project, e.Repo, e.Tag, e.Err)
+}
+
+// Result is result of a clean run.
+// - Deleted: number of tags deleted
+// - Failed: number of tags failed to delete
+// - Skipped: number of tags not attempted because their repo is skipped
+// - Protected: number of tags protected from side effect of deletion
+// - Restored: number of protected tags pushed back
+type Result struct {
+	Deleted   int         `json:"deleted"`
+	Failed    int         `json:"failed"`
+	Skipped   int         `json:"skipped"`
+	Protected int         `json:"protected"`
+	Restored  int         `json:"restored"`
+	Aborted   bool        `json:"aborted"`
+	Reason    string      `json:"reason,omitempty"`
+	Errors    []ItemError `json:"errors,omitempty"`
+}
+
+// Status gets overall status of the run.
+func (r *Result) Status() Status {
+	if r.Aborted {
+		return StatusAborted
+	}
+
+	if r.Failed == 0 && r.Skipped == 0 && len(r.Errors) == 0 {
+		return StatusSuccess
+	}
+
+	if r.Deleted == 0 {
+		return StatusTotalFailure
+	}
+
+	return StatusPartialFailure
+}
+
+// ExitCode gets process exit code corresponding to the result status.
+func (r *Result) ExitCode() int {
+	switch r.Status() {
+	case StatusSuccess:
+		return ExitSuccess
+	case StatusAborted:
+		return ExitAborted
+	case StatusTotalFailure:
+		return ExitTotalFailure
+	default:
+		return ExitPartialFailure
+	}
+}
+
+// Summary gets a one line summary of the result.
+func (r *Result) Summary() string {
+	s := fmt.Sprintf("status: %s, deleted: %d, failed: %d, skipped: %d, protected: %d, restored: %d",
+		r.Status(), r.Deleted, r.Failed, r.Skipped, r.Protected, r.Restored)
+	if len(r.Reason) > 0 {
+		s += ", reason: " + r.Reason
+	}
+	return s
+}
@@ -0,0 +1,28 @@
+package cleaner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultStatus(t *testing.T) {
+	cases := []struct {
+		result Result
+		status Status
+		code   int
+	}{
+		{Result{Deleted: 3, Protected: 1, Restored: 1}, StatusSuccess, ExitSuccess},
+		{Result{}, StatusSuccess, ExitSuccess},
+		{Result{Deleted: 2, Failed: 1}, StatusPartialFailure, ExitPartialFailure},
+		{Result{Deleted: 2, Skipped: 4}, StatusPartialFailure, ExitPartialFailure},
+		{Result{Failed: 3}, StatusTotalFailure, ExitTotalFailure},
+		{Result{Deleted: 2, Errors: []ItemError{{Op: OpRestore}}}, StatusPartialFailure, ExitPartialFailure},
+		{Result{Aborted: true}, StatusAborted, ExitAborted},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.status, c.result.Status())
+		assert.Equal(t, c.code, c.result.ExitCode())
+	}
+}
@@ -0,0 +1,138 @@
+package cleaner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+// State records progress of a clean run, it's persisted to a state file so that an interrupted
+// run can be resumed later. 'Plan' holds candidates computed at the beginning of the run, 'Done'
+// holds repos (in form of 'project/repo') that have been handled, 'Failed' maps repos to tags
+// failed to delete, and 'Protected' maps repos to manifests of protected tags not pushed back yet.
+type State struct {
+	Policy     string                          `json:"policy"`
+	StartTime  time.Time                       `json:"startTime"`
+	UpdateTime time.Time                       `json:"updateTime"`
+	Finished   bool                            `json:"finished"`
+	Plan       []*policy.Candidate             `json:"plan"`
+	Done       map[string]bool                 `json:"done"`
+	Failed     map[string][]string             `json:"failed,omitempty"`
+	Protected  map[string][]*ProtectedManifest `json:"protected,omitempty"`
+}
+
+// NewState creates a state for a new run with the given plan.
+func NewState(policyType string, plan []*policy.Candidate) *State {
+	now := time.Now()
+	return &State{
+		Policy:     policyType,
+		StartTime:  now,
+		UpdateTime: now,
+		Plan:       plan,
+		Done:       make(map[string]bool),
+		Failed:     make(map[string][]string),
+		Protected:  make(map[string][]*ProtectedManifest),
+	}
+}
+
+// LoadState loads state from the given file.
+func LoadState(file string) (*State, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &State{}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("unmarshal state file %s error: %v", file, err)
+	}
+	if state.Done == nil {
+		state.Done = make(map[string]bool)
+	}
+	if state.Failed == nil {
+		state.Failed = make(map[string][]string)
+	}
+	if state.Protected == nil {
+		state.Protected = make(map[string][]*ProtectedManifest)
+	}
+
+	return state, nil
+}
+
+// Save writes the state to the given file. It writes to a temporary file first and then renames
+// it, so an interruption in the middle won't leave a corrupted state file.
+func (s *State) Save(file string) error {
+	s.UpdateTime = time.Now()
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
+
+// Pending returns candidates in the plan that haven't been handled yet, in the order of the plan.
+func (s *State) Pending() []*policy.Candidate {
+	var pending []*policy.Candidate
+	for _, c := range s.Plan {
+		if !s.Done[repoKey(c)] {
+			pending = append(pending, c)
+		}
+	}
+
+	return pending
+}
+
+// MarkDone marks a repo as handled and records tags failed to delete in it.
+func (s *State) MarkDone(c *policy.Candidate, failed []string) {
+	key := repoKey(c)
+	s.Done[key] = true
+	if len(failed) > 0 {
+		s.Failed[key] = failed
+	}
+}
+
+// SetProtected records manifests of protected tags of a repo that need to be pushed back, the record
+// is removed if no manifests given.
+func (s *State) SetProtected(c *policy.Candidate, manifests []*ProtectedManifest) {
+	key := repoKey(c)
+	if len(manifests) == 0 {
+		delete(s.Protected, key)
+		return
+	}
+	s.Protected[key] = manifests
+}
+
+// Find finds candidate of the repo in the plan.
+func (s *State) Find(key string) *policy.Candidate {
+	for _, c := range s.Plan {
+		if repoKey(c) == key {
+			return c
+		}
+	}
+
+	return nil
+}
+
+func repoKey(c *policy.Candidate) string {
+	return fmt.Sprintf("%s/%s", c.Project, c.Repo)
+}
@@ -0,0 +1,79 @@
+package cleaner
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+func TestStateSaveAndResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "state.json")
+
+	plan := []*policy.Candidate{
+		{Project: "library", Repo: "busybox", Tags: []policy.Tag{{Name: "1.0"}}},
+		{Project: "library", Repo: "alpine", Tags: []policy.Tag{{Name: "3.8"}, {Name: "3.9"}}},
+		{Project: "release", Repo: "devops/tools", Tags: []policy.Tag{{Name: "v1.0"}}},
+	}
+	state := NewState("number", plan)
+	state.MarkDone(plan[0], nil)
+	state.MarkDone(plan[1], []string{"3.9"})
+	protected := []*ProtectedManifest{{Tags: []string{"latest"}, MediaType: "application/vnd.docker.distribution.manifest.v2+json", Payload: []byte(`{"schemaVersion": 2}`)}}
+	state.SetProtected(plan[2], protected)
+	assert.Nil(t, state.Save(file))
+
+	loaded, err := LoadState(file)
+	assert.Nil(t, err)
+	assert.Equal(t, "number", loaded.Policy)
+	assert.False(t, loaded.Finished)
+	assert.Equal(t, []string{"3.9"}, loaded.Failed["library/alpine"])
+	assert.Equal(t, protected, loaded.Protected["release/devops/tools"])
+	assert.Equal(t, plan[2].Repo, loaded.Find("release/devops/tools").Repo)
+	loaded.SetProtected(plan[2], nil)
+	assert.Equal(t, 0, len(loaded.Protected))
+
+	pending := loaded.Pending()
+	assert.Equal(t, 1, len(pending))
+	assert.Equal(t, "devops/tools", pending[0].Repo)
+}
+
+func TestQuitKeepsStateResumable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "state.json")
+
+	plan := []*policy.Candidate{
+		{Project: "library", Repo: "busybox", Tags: []policy.Tag{{Name: "1.0"}}},
+		{Project: "library", Repo: "alpine", Tags: []policy.Tag{{Name: "3.8"}}},
+	}
+	state := NewState("number", plan)
+	state.MarkDone(plan[0], nil)
+	assert.Nil(t, state.Save(file))
+
+	r := NewRunner(nil, config.C{}, Options{
+		StateFile: file,
+		Resume:    true,
+		Confirmer: NewConfirmer(strings.NewReader("q\n"), &bytes.Buffer{}),
+	})
+	result, err := r.Clean()
+	assert.Nil(t, err)
+	assert.True(t, result.Aborted)
+	assert.Equal(t, ExitAborted, result.ExitCode())
+
+	loaded, err := LoadState(file)
+	assert.Nil(t, err)
+	assert.False(t, loaded.Finished)
+	assert.Equal(t, 2, len(loaded.Plan))
+	assert.Equal(t, 1, len(loaded.Pending()))
+}
@@ -17,6 +17,20 @@ type Auth struct {
 
 type NumPolicy struct {
 	Num int `yaml:"number"`
+	// SortBy is the key to order tags to determine the newest ones, "created" (default), "pushed",
+	// "name" or "semver"
+	SortBy string `yaml:"sortBy"`
+	// PushWindow is how far back push logs are looked up when sort by "pushed", defaults to 90d. Tags
+	// not pushed within it are ordered by creation time.
+	PushWindow Duration `yaml:"pushWindow"`
+	// CountBy is what to count for the number, "tag" (default) or "digest". When count by digest,
+	// all tags pointing to the newest N digests are retained.
+	CountBy string `yaml:"countBy"`
+	// GroupBy is a regex with named capture 'group' to group tags into families, the number applies
+	// to each family. Tags not matching the regex are grouped into a family with empty name.
+	GroupBy string `yaml:"groupBy"`
+	// GroupNumbers overrides the number for families, it maps family name to number
+	GroupNumbers map[string]int `yaml:"groupNumbers"`
 }
 
 // RegexPolicy removes all images that match the given regex.
@@ -29,12 +43,159 @@ type RegexPolicy struct {
 
 // NotTouchedPolicy cleans images that are recently not touched within given period
 type NotTouchedPolicy struct {
-	// Time is time period in second.
-	Time int64 `yaml:"time"`
+	// Time is time period, e.g. '7d', '604800' (in second).
+	Time Duration `yaml:"time"`
+	// ByDigest regards all tags of an image as touched when any of its tags or its digest is touched
+	ByDigest bool `yaml:"byDigest"`
+}
+
+// AgePolicy cleans images that were built before the given period
+type AgePolicy struct {
+	// Age is the time period, e.g. '90d', '12w', '36h'. Images created earlier than it will be cleaned.
+	Age Duration `yaml:"age"`
+}
+
+// SemverPolicy retains newest releases per major or major.minor line for tags that are semantic versions
+type SemverPolicy struct {
+	// Line is granularity of release lines, "major" or "minor" (default)
+	Line string `yaml:"line"`
+	// Keep is number of newest releases to retain per line
+	Keep int `yaml:"keep"`
+	// KeepPreRelease is number of newest pre-releases to retain per line, defaults to 1, set 0 explicitly
+	// to delete all pre-releases
+	KeepPreRelease *int `yaml:"keepPreRelease"`
+	// NonSemver is rule for tags that are not semantic versions, "keep" (default) or "delete"
+	NonSemver string `yaml:"nonSemver"`
+}
+
+// BucketsPolicy retains the newest tag in each daily, weekly, monthly and yearly bucket within the
+// configured periods (grandfather-father-son). A zero period disables the bucket level.
+type BucketsPolicy struct {
+	// All is the period within which all tags are retained, e.g. '7d'
+	All Duration `yaml:"all"`
+	// Daily is the period within which the newest tag of each day is retained, e.g. '4w'
+	Daily Duration `yaml:"daily"`
+	// Weekly is the period within which the newest tag of each week is retained, e.g. '26w'
+	Weekly Duration `yaml:"weekly"`
+	// Monthly is the period within which the newest tag of each month is retained, e.g. '1095d'
+	Monthly Duration `yaml:"monthly"`
+	// Yearly is the period within which the newest tag of each year is retained
+	Yearly Duration `yaml:"yearly"`
+}
+
+// SizePolicy removes oldest tags until a repo or a project is below the storage budget. The newest
+// tag of each repo is always kept.
+type SizePolicy struct {
+	// Budget is the storage budget, e.g. '20GiB', '500MB'
+	Budget ByteSize `yaml:"budget"`
+	// Scope is what the budget applies to, "repo" (default) or "project"
+	Scope string `yaml:"scope"`
+	// DedupLayers computes usage from deduplicated layer sizes in image manifests instead of image
+	// sizes, it costs an extra API call per image.
+	DedupLayers bool `yaml:"dedupLayers"`
+}
+
+// QuotaPolicy cleans projects whose storage usage exceeds a high-water mark of the project quota
+// (Harbor 1.9+), least recently used tags are removed until the usage drops below a low-water mark.
+type QuotaPolicy struct {
+	// HighWater is percentage of the storage quota above which a project is cleaned, e.g. 85
+	HighWater int `yaml:"highWater"`
+	// LowWater is percentage of the storage quota the usage should drop below, e.g. 70
+	LowWater int `yaml:"lowWater"`
+	// RetainNumber is number of newest tags to retain in each repo anyway
+	RetainNumber int `yaml:"retainNumber"`
+	// PullWindow is the time period to look back for pulls of tags, e.g. '30d'. Zero means tags are
+	// ordered by creation time only.
+	PullWindow Duration `yaml:"pullWindow"`
+}
+
+// PopularityPolicy cleans images that are rarely used, usage is counted from access logs within
+// the window.
+type PopularityPolicy struct {
+	// Window is the time period to count usage, e.g. '30d'
+	Window Duration `yaml:"window"`
+	// MinPulls removes tags used fewer times than it
+	MinPulls int `yaml:"minPulls"`
+	// Top retains only the top N most used tags in each repo
+	Top int `yaml:"top"`
+	// Operations are access log operations counted as usage, defaults to ["pull"]
+	Operations []string `yaml:"operations"`
+	// MinAge is the age tags need to reach before removed, so new tags not yet pulled are kept, defaults to 'window'
+	MinAge Duration `yaml:"minAge"`
+}
+
+// GitPolicy cleans images of branches deleted and commits no longer reachable in a git repository.
+type GitPolicy struct {
+	// Repo is path of a local clone of the git repository, it should be fetched before the clean
+	Repo string `yaml:"repo"`
+	// Remote to look up branches in, e.g. 'origin', local branches are used if empty
+	Remote string `yaml:"remote"`
+	// TagTemplate is how images are tagged, with placeholders '{branch}' and '{commit}', e.g. '{branch}-{commit}'
+	TagTemplate string `yaml:"tagTemplate"`
+	// RevisionLabel is image label holding the commit, defaults to 'org.opencontainers.image.revision'
+	RevisionLabel string `yaml:"revisionLabel"`
+	// ProtectedBranches are branch patterns ('*' and '?' supported) whose images are always kept
+	ProtectedBranches []string `yaml:"protectedBranches"`
+	// GracePeriod keeps images built recently, as their branches or commits may not be fetched yet, defaults to '1d'
+	GracePeriod Duration `yaml:"gracePeriod"`
+}
+
+// PullRequestPolicy cleans images of pull requests that have been closed or merged, pull request
+// states are queried from a GitHub or GitLab compatible API.
+type PullRequestPolicy struct {
+	// Provider of the API, "github" (default) or "gitlab"
+	Provider string `yaml:"provider"`
+	// BaseURL of the API, e.g. 'https://api.github.com', 'https://gitlab.example.com/api/v4'
+	BaseURL string `yaml:"baseURL"`
+	// Repo is 'owner/repo' for GitHub, or project ID or path for GitLab
+	Repo string `yaml:"repo"`
+	// Token to access the API
+	Token string `yaml:"token"`
+	// TagPattern is regex with named capture 'pr' to extract pull request number from tag, defaults to '^pr-(?P<pr>[0-9]+)(-.*)?$'
+	TagPattern string `yaml:"tagPattern"`
+	// GracePeriod is time period to keep images after pull request closed or merged, e.g. '7d'
+	GracePeriod Duration `yaml:"gracePeriod"`
+}
+
+// ExpiryPolicy cleans images expired according to labels declared at build time, label
+// 'harbor-cleaner.expires-after' is a duration since image creation, e.g. '30d', and label
+// 'harbor-cleaner.expires-at' is a date like '2026-12-31' or RFC3339 time.
+type ExpiryPolicy struct {
+	// Default is the policy applies to images without expiry labels, they are kept if not configured
+	Default *Policy `yaml:"default,omitempty"`
+}
+
+// Filters scope a policy to images by metadata in the image config. Each list is patterns, '?', '*'
+// supported, and a field matches if any of its patterns matches. An image matches the filters if all
+// fields configured match.
+type Filters struct {
+	// Architectures of images, e.g. 'amd64', 'arm*'
+	Architectures []string `yaml:"architectures"`
+	// OS of images, e.g. 'linux', 'windows'
+	OS []string `yaml:"os"`
+	// Authors of images
+	Authors []string `yaml:"authors"`
+	// DockerVersions are versions of docker the images are built with, e.g. '1.*', '17.*'
+	DockerVersions []string `yaml:"dockerVersions"`
+	// DockerVersionBefore matches images built with docker earlier than the version, e.g. '18.09'
+	DockerVersionBefore string `yaml:"dockerVersionBefore"`
+}
+
+// Rule is a node of composite policy. Exactly one of 'And', 'Or', 'Not' and 'Policy' should be set,
+// 'Policy' is a leaf node that selects tags by an existing policy, while others combine child nodes.
+type Rule struct {
+	// And selects tags selected by all the child rules
+	And []*Rule `yaml:"and,omitempty"`
+	// Or selects tags selected by any of the child rules
+	Or []*Rule `yaml:"or,omitempty"`
+	// Not selects tags not selected by the child rule
+	Not *Rule `yaml:"not,omitempty"`
+	// Policy selects tags by the policy, its 'retainTags' is ignored
+	Policy *Policy `yaml:"policy,omitempty"`
 }
 
 type Policy struct {
-	// Type of the policy, e.g. "number", "regex", "recentlyNotTouched"
+	// Type of the policy, e.g. "number", "regex", "recentlyNotTouched", "age", "semver", "buckets", "size", "quota", "popularity", "git", "pullRequest", "expiry", "composite"
 	Type string `yaml:"type"`
 	// NumPolicy configures policy to retain given number tags in repo
 	NumPolicy *NumPolicy `yaml:"numberPolicy,omitempty"`
@@ -42,8 +203,46 @@ type Policy struct {
 	RegexPolicy *RegexPolicy `yaml:"regexPolicy,omitempty"`
 	// TouchPolicy configures policy to clean images that are recently not touched within given period
 	NotTouchedPolicy *NotTouchedPolicy `yaml:"notTouchedPolicy,omitempty"`
+	// AgePolicy configures policy to clean images that are created before given period
+	AgePolicy *AgePolicy `yaml:"agePolicy,omitempty"`
+	// SemverPolicy configures policy to retain newest releases per release line
+	SemverPolicy *SemverPolicy `yaml:"semverPolicy,omitempty"`
+	// BucketsPolicy configures policy to retain newest tag in each time bucket
+	BucketsPolicy *BucketsPolicy `yaml:"bucketsPolicy,omitempty"`
+	// SizePolicy configures policy to keep repos or projects under a storage budget
+	SizePolicy *SizePolicy `yaml:"sizePolicy,omitempty"`
+	// QuotaPolicy configures policy to keep projects under high-water mark of their storage quota
+	QuotaPolicy *QuotaPolicy `yaml:"quotaPolicy,omitempty"`
+	// PopularityPolicy configures policy to clean images that are rarely pulled
+	PopularityPolicy *PopularityPolicy `yaml:"popularityPolicy,omitempty"`
+	// GitPolicy configures policy to clean images of deleted branches and unreachable commits
+	GitPolicy *GitPolicy `yaml:"gitPolicy,omitempty"`
+	// PullRequestPolicy configures policy to clean images of closed or merged pull requests
+	PullRequestPolicy *PullRequestPolicy `yaml:"pullRequestPolicy,omitempty"`
+	// ExpiryPolicy configures policy to clean images expired by their expiry labels
+	ExpiryPolicy *ExpiryPolicy `yaml:"expiryPolicy,omitempty"`
+	// CompositePolicy configures policy to combine other policies with and/or/not
+	CompositePolicy *Rule `yaml:"compositePolicy,omitempty"`
 	// RetainTags is tag patterns to be retained
 	RetainTags []string `yaml:"retainTags"`
+	// LabelSelector scopes the policy to images whose config labels match, Kubernetes label selector
+	// syntax, e.g. 'team=data,retention!=permanent'. Images not matched are never removed by the policy.
+	LabelSelector string `yaml:"labelSelector,omitempty"`
+	// Filters scopes the policy to images whose metadata match, e.g. architecture, OS, author.
+	// Images not matched are never removed by the policy.
+	Filters *Filters `yaml:"filters,omitempty"`
+}
+
+// ScopedPolicy is a policy that applies to matched projects and repos instead of the global policy.
+type ScopedPolicy struct {
+	// Name of the scope, shown in dry run output, defaults to 'scopedPolicies[<index>]'
+	Name string `yaml:"name"`
+	// Projects are project name patterns ('*' and '?' supported) the policy applies to
+	Projects []string `yaml:"projects"`
+	// Repos are optional 'project/repo' patterns to further restrict the scope, as in 'repos' of C
+	Repos []string `yaml:"repos"`
+	// Policy to apply, its 'retainTags' are merged with those of the global policy
+	Policy Policy `yaml:"policy"`
 }
 
 type Trigger struct {
@@ -56,14 +255,51 @@ type XSRF struct {
 	Key     string `yaml:"key"`
 }
 
+// Safety configures limits that abort a clean run before any deletion happens.
+type Safety struct {
+	// MaxDeletions is the maximum number of tags allowed to delete in a run, 0 means no limit.
+	MaxDeletions int `yaml:"maxDeletions"`
+}
+
+// AccessLogCache configures a local cache of Harbor access logs, so that logs are fetched
+// incrementally instead of on every run.
+type AccessLogCache struct {
+	// File of the embedded database to store the cached access logs
+	File string `yaml:"file"`
+}
+
+// KubernetesProtection configures a Kubernetes cluster whose workloads' images are protected.
+type KubernetesProtection struct {
+	// Kubeconfig is path of the kubeconfig file, in-cluster config is used if empty
+	Kubeconfig string `yaml:"kubeconfig"`
+	// Context in the kubeconfig to use, defaults to the current context
+	Context string `yaml:"context"`
+}
+
+// Protection configures sources of images that should never be cleaned, e.g. images in use.
+type Protection struct {
+	// Hosts are extra registry hosts of Harbor in image references, host of Harbor is always included
+	Hosts []string `yaml:"hosts"`
+	// Kubernetes are clusters whose workloads' images are protected
+	Kubernetes []*KubernetesProtection `yaml:"kubernetes"`
+	// ManifestDirs are directories of deployment manifests whose referenced images are protected
+	ManifestDirs []string `yaml:"manifestDirs"`
+}
+
 type C struct {
 	Host     string   `yaml:"host"`
 	Version  string   `yaml:"version"`
 	Auth     Auth     `yaml:"auth"`
 	Projects []string `yaml:"projects"`
+	Repos    []string `yaml:"repos"`
 	Policy   Policy   `yaml:"policy"`
-	Trigger  *Trigger `yaml:"trigger"`
-	XSRF     XSRF     `yaml:"xsrf"`
+	// ScopedPolicies overrides the global policy for matched projects and repos, the first matched wins
+	ScopedPolicies []*ScopedPolicy `yaml:"scopedPolicies"`
+	Trigger        *Trigger        `yaml:"trigger"`
+	XSRF           XSRF            `yaml:"xsrf"`
+	Safety         Safety          `yaml:"safety"`
+	AccessLogCache *AccessLogCache `yaml:"accessLogCache"`
+	Protection     *Protection     `yaml:"protection"`
 }
 
 var Config = C{}
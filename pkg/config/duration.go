@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is a time period configured in human readable form like '90d', '12w', '36h' or '1d12h'.
+// Supported units are 's', 'm', 'h', 'd' (24 hours) and 'w' (7 days). A plain integer is regarded
+// as seconds for backward compatibility.
+type Duration time.Duration
+
+// durationUnits are units supported in Duration.
+var durationUnits = map[byte]time.Duration{
+	's': time.Second,
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': time.Hour * 24,
+	'w': time.Hour * 24 * 7,
+}
+
+// maxDuration is the longest duration can be represented.
+const maxDuration = time.Duration(math.MaxInt64)
+
+// ParseDuration parses a duration string like '90d', '12w', '36h', '1w2d' or '604800'. Negative
+// durations are rejected.
+func ParseDuration(s string) (Duration, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	if strings.HasPrefix(s, "-") {
+		return 0, fmt.Errorf("negative duration '%s' not allowed", s)
+	}
+
+	// Sign is not permitted in unsigned integer, so '+5' is rejected as well
+	if seconds, err := strconv.ParseUint(s, 10, 64); err == nil {
+		if seconds > uint64(maxDuration/time.Second) {
+			return 0, fmt.Errorf("duration '%s' out of range", s)
+		}
+		return Duration(time.Duration(seconds) * time.Second), nil
+	}
+
+	var d time.Duration
+	rest := s
+	for len(rest) > 0 {
+		i := 0
+		for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+			i++
+		}
+		if i == 0 || i == len(rest) {
+			return 0, fmt.Errorf("invalid duration '%s', e.g. '90d', '12w', '36h' expected", s)
+		}
+
+		n, err := strconv.ParseInt(rest[:i], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration '%s': %v", s, err)
+		}
+		unit, ok := durationUnits[rest[i]]
+		if !ok {
+			return 0, fmt.Errorf("invalid unit '%c' in duration '%s', supported units are s, m, h, d, w", rest[i], s)
+		}
+
+		if n > int64((maxDuration-d)/unit) {
+			return 0, fmt.Errorf("duration '%s' out of range", s)
+		}
+		d += time.Duration(n) * unit
+		rest = rest[i+1:]
+	}
+
+	return Duration(d), nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	parsed, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+
+	return nil
+}
+
+// Duration converts to time.Duration
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// Seconds gets the duration in seconds
+func (d Duration) Seconds() int64 {
+	return int64(time.Duration(d) / time.Second)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseDuration(t *testing.T) {
+	cases := []struct {
+		s        string
+		expected time.Duration
+	}{
+		{"604800", time.Hour * 24 * 7},
+		{"90d", time.Hour * 24 * 90},
+		{"12w", time.Hour * 24 * 7 * 12},
+		{"36h", time.Hour * 36},
+		{"1d12h", time.Hour * 36},
+		{"30m", time.Minute * 30},
+		{"106751d", time.Hour * 24 * 106751},
+	}
+	for _, c := range cases {
+		d, err := ParseDuration(c.s)
+		assert.Nil(t, err)
+		assert.Equal(t, c.expected, d.Duration())
+	}
+
+	for _, s := range []string{"", "d", "90x", "1.5d", "90dd", "-1d", "-5", "-0", "+5", "-1d12h", "99999999999d", "106752d", "99999999999999999999d", "9223372036854775807", "106751d1d"} {
+		_, err := ParseDuration(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestUnmarshalDuration(t *testing.T) {
+	p := &NotTouchedPolicy{}
+	assert.Nil(t, yaml.Unmarshal([]byte("time: 604800"), p))
+	assert.Equal(t, int64(604800), p.Time.Seconds())
+
+	assert.Nil(t, yaml.Unmarshal([]byte("time: 7d"), p))
+	assert.Equal(t, int64(604800), p.Time.Seconds())
+
+	assert.NotNil(t, yaml.Unmarshal([]byte("time: 7days"), p))
+}
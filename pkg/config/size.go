@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a storage size configured in human readable form like '20GiB', '500MB' or '1.5TiB'.
+// Decimal units (KB, MB, GB, TB) and binary units (KiB, MiB, GiB, TiB) are supported, a plain
+// integer is regarded as bytes.
+type ByteSize int64
+
+// sizeUnits are units supported in ByteSize, units are case insensitive.
+var sizeUnits = map[string]int64{
+	"b":   1,
+	"kb":  1000,
+	"mb":  1000 * 1000,
+	"gb":  1000 * 1000 * 1000,
+	"tb":  1000 * 1000 * 1000 * 1000,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+// ParseByteSize parses a size string like '20GiB', '500MB', '1.5TiB' or '1073741824'.
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return 0, fmt.Errorf("empty size")
+	}
+
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if n < 0 {
+			return 0, fmt.Errorf("invalid size '%s', it should not be negative", s)
+		}
+		return ByteSize(n), nil
+	}
+
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+		i++
+	}
+	if i == 0 {
+		return 0, fmt.Errorf("invalid size '%s', e.g. '20GiB', '500MB' expected", s)
+	}
+
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size '%s': %v", s, err)
+	}
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid unit in size '%s', supported units are B, KB, MB, GB, TB, KiB, MiB, GiB, TiB", s)
+	}
+
+	return ByteSize(n * float64(unit)), nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (b *ByteSize) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	parsed, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = parsed
+
+	return nil
+}
+
+func (b ByteSize) String() string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	v := float64(b)
+	i := 0
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%dB", int64(b))
+	}
+	return fmt.Sprintf("%.1f%s", v, units[i])
+}
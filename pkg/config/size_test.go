@@ -0,0 +1,39 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseByteSize(t *testing.T) {
+	cases := []struct {
+		s        string
+		expected int64
+	}{
+		{"1073741824", 1 << 30},
+		{"20GiB", 20 << 30},
+		{"500MB", 500 * 1000 * 1000},
+		{"1.5TiB", 3 << 39},
+		{"512 kib", 512 << 10},
+		{"0", 0},
+	}
+	for _, c := range cases {
+		b, err := ParseByteSize(c.s)
+		assert.Nil(t, err)
+		assert.Equal(t, ByteSize(c.expected), b)
+	}
+
+	for _, s := range []string{"", "GiB", "20XB", "1..5GiB", "-1"} {
+		_, err := ParseByteSize(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestUnmarshalByteSize(t *testing.T) {
+	p := &SizePolicy{}
+	assert.Nil(t, yaml.Unmarshal([]byte("budget: 20GiB"), p))
+	assert.Equal(t, ByteSize(20<<30), p.Budget)
+	assert.Equal(t, "20.0GiB", p.Budget.String())
+}
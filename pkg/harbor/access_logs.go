@@ -11,11 +11,17 @@ import (
 
 // ListAllAccessLogs get all access logs from Harbor
 func (c *Client) ListAllAccessLogs(startTime, endTime int64) ([]*AccessLog, error) {
+	return c.ListAllAccessLogsOfOperation(startTime, endTime, "")
+}
+
+// ListAllAccessLogsOfOperation get all access logs of the given operation from Harbor, e.g. 'push',
+// if operation is empty, logs of all operations are returned.
+func (c *Client) ListAllAccessLogsOfOperation(startTime, endTime int64, operation string) ([]*AccessLog, error) {
 	var page int64 = 1
 	var pageSize int64 = 500
 	var logs []*AccessLog
 	for {
-		pageLogs, err := c.listAccessLogsPage(startTime, endTime, page, pageSize)
+		pageLogs, err := c.listAccessLogsPage(startTime, endTime, operation, page, pageSize)
 		if err != nil {
 			return nil, err
 		}
@@ -31,8 +37,8 @@ func (c *Client) ListAllAccessLogs(startTime, endTime int64) ([]*AccessLog, erro
 	return logs, nil
 }
 
-func (c *Client) listAccessLogsPage(startTime, endTime, page, pageSize int64) ([]*AccessLog, error) {
-	path := AccessLogsPath(startTime, endTime, "", page, pageSize)
+func (c *Client) listAccessLogsPage(startTime, endTime int64, operation string, page, pageSize int64) ([]*AccessLog, error) {
+	path := AccessLogsPath(startTime, endTime, operation, page, pageSize)
 
 	logrus.Infof("%s %s", http.MethodGet, path)
 	resp, err := c.do(http.MethodGet, path, nil)
@@ -14,6 +14,7 @@ const (
 	APIImageManifest = "/api/repositories/%s/%s/tags/%s/manifest"
 	APITarget        = "/api/targets/%d"
 	APIAccessLogs    = "/api/logs"
+	APIQuotas        = "/api/quotas"
 )
 
 func ProjectsPath(page, pageSize int, name, public string) string {
@@ -58,3 +59,7 @@ func TargetPath(tid int64) string {
 func AccessLogsPath(startTime, endTime int64, operation string, page, pageSize int64) string {
 	return fmt.Sprintf("%s?begin_timestamp=%d&end_timestamp=%d&operation=%s&page=%d&page_size=%d", APIAccessLogs, startTime, endTime, operation, page, pageSize)
 }
+
+func QuotasPath(page, pageSize int) string {
+	return fmt.Sprintf("%s?reference=project&page=%d&page_size=%d", APIQuotas, page, pageSize)
+}
@@ -0,0 +1,62 @@
+package harbor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ListQuotas gets a page of project quotas, it's supported since Harbor 1.9.
+func (c *Client) ListQuotas(page, pageSize int) (int, []*Quota, error) {
+	path := QuotasPath(page, pageSize)
+
+	logrus.Infof("%s %s", http.MethodGet, path)
+	resp, err := c.do(http.MethodGet, path, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if resp.StatusCode/100 == 2 {
+		ret := make([]*Quota, 0)
+		err := json.Unmarshal(body, &ret)
+		if err != nil {
+			return 0, nil, err
+		}
+		total, err := getTotalFromResp(resp)
+		if err != nil {
+			logrus.Errorf("get total from resp error: %v", err)
+			return 0, nil, err
+		}
+		return total, ret, nil
+	}
+	logrus.Errorf("list harbor quotas error: %s", body)
+
+	return 0, nil, fmt.Errorf("%s", body)
+}
+
+// AllQuotas gets quotas of all projects.
+func (c *Client) AllQuotas() ([]*Quota, error) {
+	page := 1
+	ret := make([]*Quota, 0)
+	for {
+		total, quotas, err := c.ListQuotas(page, MaxPageSize)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, quotas...)
+		if total <= page*MaxPageSize {
+			break
+		}
+		page++
+	}
+	return ret, nil
+}
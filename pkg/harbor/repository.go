@@ -24,8 +24,8 @@ func (c *Client) getRepos(projectId int64, query string, page, pageSize int) (in
 	}
 
 	if resp.StatusCode/100 != 2 {
-		logrus.Errorf("list harbor repositories from projectId: %s error: %s, StatusCode: %d", projectId, body, resp.StatusCode)
-		return 0, nil, fmt.Errorf(string(body))
+		logrus.Errorf("list harbor repositories from projectId: %d error: %s, StatusCode: %d", projectId, body, resp.StatusCode)
+		return 0, nil, fmt.Errorf("%s", body)
 	}
 
 	repos := make([]*Repo, 0)
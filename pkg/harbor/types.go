@@ -6,6 +6,8 @@ import (
 
 const (
 	AccessOperationDelete = "delete"
+	AccessOperationPush   = "push"
+	AccessOperationPull   = "pull"
 )
 
 // HarborProject holds details of a project.
@@ -72,9 +74,28 @@ type TagLayers struct {
 }
 
 type AccessLog struct {
-	LogID     int64  `json:"log_id"`
-	ProjectID int64  `json:"project_id"`
-	RepoName  string `json:"repo_name"`
-	Tag       string `json:"repo_tag"`
-	Operation string `json:"operation"`
+	LogID     int64     `json:"log_id"`
+	ProjectID int64     `json:"project_id"`
+	RepoName  string    `json:"repo_name"`
+	Tag       string    `json:"repo_tag"`
+	Operation string    `json:"operation"`
+	OpTime    time.Time `json:"op_time"`
+}
+
+// Quota is resource quota of a project, available since Harbor 1.9. Negative hard limit means unlimited.
+type Quota struct {
+	ID   int64          `json:"id"`
+	Ref  QuotaRef       `json:"ref"`
+	Hard QuotaResources `json:"hard"`
+	Used QuotaResources `json:"used"`
+}
+
+type QuotaRef struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type QuotaResources struct {
+	Count   int64 `json:"count"`
+	Storage int64 `json:"storage"`
 }
@@ -22,7 +22,7 @@ func getTotalFromResp(resp *http.Response) (int, error) {
 
 	total, err := strconv.Atoi(totalStr)
 	if err != nil {
-		logrus.Errorf("strconv.Atoi error: %v, resp header %s is %s", RespHeaderTotal, totalStr)
+		logrus.Errorf("strconv.Atoi error: %v, resp header %s is %s", err, RespHeaderTotal, totalStr)
 		return 0, err
 	}
 
@@ -0,0 +1,245 @@
+package logcache
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+)
+
+// Buckets and keys in the cache database.
+var (
+	// logsBucket maps log ID to the log in JSON
+	logsBucket = []byte("logs")
+	// timesBucket indexes logs by operation time, keys are operation time followed by log ID
+	timesBucket = []byte("times")
+	metaBucket  = []byte("meta")
+	sinceKey    = []byte("since")
+	untilKey    = []byte("until")
+)
+
+// openTimeout is how long to wait for the lock of the cache file held by another run.
+const openTimeout = time.Second * 10
+
+// Source is where access logs are fetched from, it's implemented by harbor.Client.
+type Source interface {
+	ListAllAccessLogsOfOperation(startTime, endTime int64, operation string) ([]*harbor.AccessLog, error)
+}
+
+// Cache is a local store of Harbor access logs in an embedded database file, logs are keyed by log
+// ID and indexed by operation time. It's synced incrementally, only logs since the last sync are
+// fetched and appended, and logs out of the retention are pruned. Logs in the range [since, until]
+// (unix seconds) are complete in the cache.
+type Cache struct {
+	db    *bolt.DB
+	since int64
+	until int64
+}
+
+// Open opens the cache database in the given file, it's created if not exist.
+func Open(file string) (*Cache, error) {
+	db, err := bolt.Open(file, 0600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("open access log cache %s error: %v", file, err)
+	}
+
+	c := &Cache{db: db}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{logsBucket, timesBucket, metaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+
+		meta := tx.Bucket(metaBucket)
+		c.since = decodeInt(meta.Get(sinceKey))
+		c.until = decodeInt(meta.Get(untilKey))
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init access log cache %s error: %v", file, err)
+	}
+
+	return c, nil
+}
+
+// Close closes the cache database.
+func (c *Cache) Close() error {
+	return c.db.Close()
+}
+
+// Sync fetches logs since the last sync from the source, appends them and prunes logs older than the
+// retention in one transaction. If the cache is empty, or it doesn't cover the retention, it's reset
+// and all logs within the retention are fetched.
+func (c *Cache) Sync(src Source, now time.Time, retention time.Duration) error {
+	start := now.Add(-retention).Unix()
+	from := c.until
+	reset := c.until == 0 || c.until < start || start < c.since
+	if reset {
+		from = start
+	}
+
+	// Logs at 'from' are fetched again as logs of the same second may arrive after the last sync,
+	// they are deduplicated by log ID.
+	logs, err := src.ListAllAccessLogsOfOperation(from, now.Unix(), "")
+	if err != nil {
+		return err
+	}
+
+	since := c.since
+	if reset || since < start {
+		since = start
+	}
+	until := now.Unix()
+	err = c.db.Update(func(tx *bolt.Tx) error {
+		if reset {
+			for _, name := range [][]byte{logsBucket, timesBucket} {
+				if err := tx.DeleteBucket(name); err != nil {
+					return err
+				}
+				if _, err := tx.CreateBucket(name); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := appendLogs(tx, logs); err != nil {
+			return err
+		}
+		if err := prune(tx, start); err != nil {
+			return err
+		}
+
+		meta := tx.Bucket(metaBucket)
+		if err := meta.Put(sinceKey, encodeInt(since)); err != nil {
+			return err
+		}
+		return meta.Put(untilKey, encodeInt(until))
+	})
+	if err != nil {
+		return fmt.Errorf("save access logs to cache error: %v", err)
+	}
+
+	c.since = since
+	c.until = until
+	return nil
+}
+
+// appendLogs puts logs not in the cache yet.
+func appendLogs(tx *bolt.Tx, logs []*harbor.AccessLog) error {
+	byID := tx.Bucket(logsBucket)
+	byTime := tx.Bucket(timesBucket)
+	for _, log := range logs {
+		id := encodeInt(log.LogID)
+		if byID.Get(id) != nil {
+			continue
+		}
+
+		b, err := json.Marshal(log)
+		if err != nil {
+			return err
+		}
+		if err := byID.Put(id, b); err != nil {
+			return err
+		}
+		if err := byTime.Put(timeKey(log.OpTime.Unix(), log.LogID), nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prune deletes logs before the start time.
+func prune(tx *bolt.Tx, start int64) error {
+	byID := tx.Bucket(logsBucket)
+	byTime := tx.Bucket(timesBucket)
+
+	// Keys are collected first, as deleting in the middle of cursor iteration skips keys.
+	var expired [][]byte
+	cursor := byTime.Cursor()
+	for k, _ := cursor.First(); k != nil && decodeInt(k[:8]) < start; k, _ = cursor.Next() {
+		expired = append(expired, k)
+	}
+
+	for _, k := range expired {
+		if err := byID.Delete(k[8:]); err != nil {
+			return err
+		}
+		if err := byTime.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Covers checks whether logs since the given time are complete in the cache.
+func (c *Cache) Covers(startTime int64) bool {
+	return c.until > 0 && startTime >= c.since
+}
+
+// Query gets logs of the operation in the time range ordered by log ID, logs of all operations are
+// returned if operation is empty.
+func (c *Cache) Query(startTime, endTime int64, operation string) ([]*harbor.AccessLog, error) {
+	if startTime < 0 {
+		startTime = 0
+	}
+
+	var logs []*harbor.AccessLog
+	err := c.db.View(func(tx *bolt.Tx) error {
+		byID := tx.Bucket(logsBucket)
+		cursor := tx.Bucket(timesBucket).Cursor()
+		for k, _ := cursor.Seek(timeKey(startTime, 0)); k != nil && decodeInt(k[:8]) <= endTime; k, _ = cursor.Next() {
+			b := byID.Get(k[8:])
+			if b == nil {
+				continue
+			}
+
+			log := &harbor.AccessLog{}
+			if err := json.Unmarshal(b, log); err != nil {
+				return err
+			}
+			if len(operation) > 0 && log.Operation != operation {
+				continue
+			}
+			logs = append(logs, log)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("query access log cache error: %v", err)
+	}
+
+	sort.Slice(logs, func(i, j int) bool {
+		return logs[i].LogID < logs[j].LogID
+	})
+	return logs, nil
+}
+
+// timeKey is key of the operation time index, big endian encoding keeps keys in time order.
+func timeKey(t, id int64) []byte {
+	k := make([]byte, 16)
+	binary.BigEndian.PutUint64(k, uint64(t))
+	binary.BigEndian.PutUint64(k[8:], uint64(id))
+	return k
+}
+
+func encodeInt(n int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(n))
+	return b
+}
+
+func decodeInt(b []byte) int64 {
+	if len(b) != 8 {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
@@ -0,0 +1,86 @@
+package logcache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+)
+
+type fakeSource struct {
+	logs  []*harbor.AccessLog
+	calls [][2]int64
+}
+
+func (s *fakeSource) ListAllAccessLogsOfOperation(startTime, endTime int64, operation string) ([]*harbor.AccessLog, error) {
+	s.calls = append(s.calls, [2]int64{startTime, endTime})
+	var logs []*harbor.AccessLog
+	for _, log := range s.logs {
+		if t := log.OpTime.Unix(); t >= startTime && t <= endTime {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+func TestSync(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logcache")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "logs.db")
+
+	now := time.Unix(1600000000, 0)
+	src := &fakeSource{logs: []*harbor.AccessLog{
+		{LogID: 1, Tag: "v1", Operation: harbor.AccessOperationPush, OpTime: now.Add(-time.Hour * 3)},
+		{LogID: 2, Tag: "v1", Operation: harbor.AccessOperationPull, OpTime: now.Add(-time.Hour)},
+	}}
+
+	c, err := Open(file)
+	assert.Nil(t, err)
+	assert.False(t, c.Covers(0))
+	assert.Nil(t, c.Sync(src, now, time.Hour*2))
+	assert.Equal(t, [2]int64{now.Add(-time.Hour * 2).Unix(), now.Unix()}, src.calls[0])
+	logs, err := c.Query(0, now.Unix(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(logs))
+	assert.Nil(t, c.Close())
+
+	later := now.Add(time.Minute * 90)
+	src.logs = append(src.logs, &harbor.AccessLog{LogID: 3, Tag: "v2", Operation: harbor.AccessOperationPull, OpTime: later})
+
+	c, err = Open(file)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Sync(src, later, time.Hour*3))
+	assert.Equal(t, [2]int64{now.Unix(), later.Unix()}, src.calls[1])
+	assert.True(t, c.Covers(later.Add(-time.Hour*2).Unix()))
+	assert.False(t, c.Covers(0))
+
+	logs, err = c.Query(0, later.Unix(), harbor.AccessOperationPull)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(logs))
+	assert.Equal(t, int64(2), logs[0].LogID)
+	assert.Equal(t, int64(3), logs[1].LogID)
+	assert.Equal(t, "v2", logs[1].Tag)
+
+	// Logs fetched again are deduplicated by log ID, logs out of the retention are pruned
+	latest := later.Add(time.Hour * 2)
+	assert.Nil(t, c.Sync(src, latest, time.Hour*3))
+	assert.Equal(t, [2]int64{later.Unix(), latest.Unix()}, src.calls[2])
+	logs, err = c.Query(0, latest.Unix(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(logs))
+	assert.Equal(t, int64(3), logs[0].LogID)
+
+	// Retention extended beyond the cache, it's reset and synced from the start of the retention
+	assert.Nil(t, c.Sync(src, latest, time.Hour*5))
+	assert.Equal(t, [2]int64{latest.Add(-time.Hour * 5).Unix(), latest.Unix()}, src.calls[3])
+	logs, err = c.Query(0, latest.Unix(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(logs))
+	assert.Nil(t, c.Close())
+}
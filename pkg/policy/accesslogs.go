@@ -0,0 +1,159 @@
+package policy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+	"github.com/cd1989/harbor-cleaner/pkg/logcache"
+)
+
+// syncInterval is the minimal interval to sync access log cache, so that policies evaluated in the
+// same run share one sync.
+const syncInterval = time.Minute
+
+var (
+	cacheLock sync.Mutex
+	caches    = make(map[string]*cachedLogs)
+)
+
+type cachedLogs struct {
+	cache  *logcache.Cache
+	synced time.Time
+	// retention is computed from the root config of the run, processors of leaf policies, e.g. in
+	// composite rules, only see their own part of the config.
+	retention time.Duration
+}
+
+// SetLogRetention sets retention of the access log cache from the root config of the run, so that
+// the cache is not pruned to the window of the first leaf policy synced it.
+func SetLogRetention(cfg config.C) {
+	if cfg.AccessLogCache == nil || len(cfg.AccessLogCache.File) == 0 {
+		return
+	}
+
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	cachedEntry(cfg.AccessLogCache.File).retention = LogRetention(cfg)
+}
+
+// cachedEntry gets the entry of the cache file, it should be called with the lock held.
+func cachedEntry(file string) *cachedLogs {
+	cached, ok := caches[file]
+	if !ok {
+		cached = &cachedLogs{}
+		caches[file] = cached
+	}
+	return cached
+}
+
+// ListAccessLogs lists access logs of the operation in the time range, logs of all operations are
+// listed if operation is empty. If access log cache is configured, logs are queried from the cache
+// after it's synced, unless the time range is beyond retention of the cache.
+func (p *BaseProcessor) ListAccessLogs(startTime, endTime int64, operation string) ([]*harbor.AccessLog, error) {
+	if p.Cfg.AccessLogCache == nil || len(p.Cfg.AccessLogCache.File) == 0 {
+		return p.Client.ListAllAccessLogsOfOperation(startTime, endTime, operation)
+	}
+
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+
+	file := p.Cfg.AccessLogCache.File
+	cached := cachedEntry(file)
+	retention := LogRetention(p.Cfg)
+	if cached.retention > retention {
+		retention = cached.retention
+	}
+	if retention <= 0 {
+		return p.Client.ListAllAccessLogsOfOperation(startTime, endTime, operation)
+	}
+
+	if cached.cache == nil {
+		cache, err := logcache.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		cached.cache = cache
+	}
+
+	now := time.Now()
+	if now.Sub(cached.synced) >= syncInterval {
+		// Retention is extended by the sync interval, so that time ranges computed after the sync are still covered.
+		if err := cached.cache.Sync(p.Client, now, retention+syncInterval); err != nil {
+			return nil, err
+		}
+		cached.synced = now
+	}
+
+	if !cached.cache.Covers(startTime) {
+		logrus.Infof("Time range of access logs is beyond retention of the cache, list from Harbor")
+		return p.Client.ListAllAccessLogsOfOperation(startTime, endTime, operation)
+	}
+
+	return cached.cache.Query(startTime, endTime, operation)
+}
+
+// LogRetention gets the longest time window of access logs used by policies in the config,
+// including scoped policies, policies in composite rules and default policies of expiry policies.
+func LogRetention(cfg config.C) time.Duration {
+	retention := policyLogWindow(&cfg.Policy)
+	for _, scoped := range cfg.ScopedPolicies {
+		if w := policyLogWindow(&scoped.Policy); w > retention {
+			retention = w
+		}
+	}
+
+	return retention
+}
+
+func policyLogWindow(p *config.Policy) time.Duration {
+	switch Type(p.Type) {
+	case RecentlyNotTouchedPolicy:
+		if p.NotTouchedPolicy != nil {
+			return p.NotTouchedPolicy.Time.Duration()
+		}
+	case PopularityPolicy:
+		if p.PopularityPolicy != nil {
+			return p.PopularityPolicy.Window.Duration()
+		}
+	case QuotaPolicy:
+		if p.QuotaPolicy != nil {
+			return p.QuotaPolicy.PullWindow.Duration()
+		}
+	case ExpiryPolicy:
+		if p.ExpiryPolicy != nil && p.ExpiryPolicy.Default != nil {
+			return policyLogWindow(p.ExpiryPolicy.Default)
+		}
+	case CompositePolicy:
+		return ruleLogWindow(p.CompositePolicy)
+	}
+
+	return 0
+}
+
+func ruleLogWindow(rule *config.Rule) time.Duration {
+	if rule == nil {
+		return 0
+	}
+
+	var window time.Duration
+	var children []*config.Rule
+	children = append(children, rule.And...)
+	children = append(children, rule.Or...)
+	children = append(children, rule.Not)
+	for _, child := range children {
+		if w := ruleLogWindow(child); w > window {
+			window = w
+		}
+	}
+	if rule.Policy != nil {
+		if w := policyLogWindow(rule.Policy); w > window {
+			window = w
+		}
+	}
+
+	return window
+}
@@ -0,0 +1,59 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+)
+
+func TestLogRetention(t *testing.T) {
+	day := time.Hour * 24
+	cfg := config.C{
+		Policy: config.Policy{
+			Type:             string(CompositePolicy),
+			NotTouchedPolicy: &config.NotTouchedPolicy{Time: config.Duration(day * 365)},
+			CompositePolicy: &config.Rule{
+				And: []*config.Rule{
+					{Policy: &config.Policy{Type: string(AgePolicy)}},
+					{Not: &config.Rule{Policy: &config.Policy{
+						Type:             string(RecentlyNotTouchedPolicy),
+						NotTouchedPolicy: &config.NotTouchedPolicy{Time: config.Duration(day * 7)},
+					}}},
+				},
+			},
+		},
+		ScopedPolicies: []*config.ScopedPolicy{
+			{Policy: config.Policy{
+				Type:             string(PopularityPolicy),
+				PopularityPolicy: &config.PopularityPolicy{Window: config.Duration(day * 30)},
+			}},
+		},
+	}
+	assert.Equal(t, day*30, LogRetention(cfg))
+
+	cfg.ScopedPolicies = nil
+	assert.Equal(t, day*7, LogRetention(cfg))
+
+	cfg.Policy.Type = string(NumberLimitPolicy)
+	assert.Equal(t, time.Duration(0), LogRetention(cfg))
+}
+
+func TestSetLogRetention(t *testing.T) {
+	day := time.Hour * 24
+	cfg := config.C{
+		Policy: config.Policy{
+			Type:             string(PopularityPolicy),
+			PopularityPolicy: &config.PopularityPolicy{Window: config.Duration(day * 90)},
+		},
+		AccessLogCache: &config.AccessLogCache{File: "test-set-log-retention.json"},
+	}
+	SetLogRetention(cfg)
+	assert.Equal(t, day*90, caches[cfg.AccessLogCache.File].retention)
+
+	SetLogRetention(config.C{})
+	assert.Equal(t, day*90, caches[cfg.AccessLogCache.File].retention)
+	delete(caches, cfg.AccessLogCache.File)
+}
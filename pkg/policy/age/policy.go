@@ -0,0 +1,86 @@
+package age
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+func init() {
+	policy.RegisterProcessorFactory(policy.AgePolicy, newFactory())
+}
+
+func newFactory() func(cfg config.C) policy.Processor {
+	return func(cfg config.C) policy.Processor {
+		return &agePolicyProcessor{
+			BaseProcessor: policy.BaseProcessor{
+				Client: harbor.APIClient,
+				Cfg:    cfg,
+			},
+			now: time.Now,
+		}
+	}
+}
+
+type agePolicyProcessor struct {
+	policy.BaseProcessor
+	now func() time.Time
+}
+
+// Ensure (*agePolicyProcessor) implements interface Processor
+var _ policy.Processor = (*agePolicyProcessor)(nil)
+
+// GetPolicyType gets policy type.
+func (p *agePolicyProcessor) GetPolicyType() policy.Type {
+	return policy.AgePolicy
+}
+
+// Validate validates the policy configuration.
+func (p *agePolicyProcessor) Validate() error {
+	if p.Cfg.Policy.AgePolicy == nil {
+		return fmt.Errorf("policy.agePolicy not configured, it's necessary when policy.type == 'age'")
+	}
+
+	if p.Cfg.Policy.AgePolicy.Age <= 0 {
+		return fmt.Errorf("policy.agePolicy.age should be positive")
+	}
+
+	return nil
+}
+
+// ListCandidates list all candidates to be remove based on the policy
+func (p *agePolicyProcessor) ListCandidates() ([]*policy.Candidate, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	images, err := p.ListTags()
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := p.Evaluate(images)
+	if err != nil {
+		return nil, err
+	}
+
+	return policy.BuildCandidates(images, selected, p.Cfg.Policy.RetainTags), nil
+}
+
+// Evaluate selects tags to remove from the given repos
+func (p *agePolicyProcessor) Evaluate(images []*policy.RepoTags) (policy.Selection, error) {
+	deadline := p.now().Add(-p.Cfg.Policy.AgePolicy.Age.Duration())
+	selected := make(policy.Selection)
+	for _, r := range images {
+		for _, t := range r.Tags {
+			if t.Created.Before(deadline) {
+				selected.Add(r, t.Name)
+			}
+		}
+	}
+
+	return selected, nil
+}
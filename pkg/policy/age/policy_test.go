@@ -0,0 +1,56 @@
+package age
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+func TestEvaluate(t *testing.T) {
+	day := time.Hour * 24
+	now := time.Now()
+	repo := &policy.RepoTags{
+		Project: "library",
+		Repo:    "app",
+		Tags: []policy.Tag{
+			{Name: "v5", Created: now},
+			{Name: "v4", Created: now.Add(-day*30 + time.Second)},
+			{Name: "v3", Created: now.Add(-day * 30)},
+			{Name: "v2", Created: now.Add(-day*30 - time.Second)},
+			{Name: "v1", Created: now.Add(-day * 90)},
+		},
+	}
+
+	cases := []struct {
+		age      config.Duration
+		expected []string
+	}{
+		{config.Duration(day * 30), []string{"v2", "v1"}},
+		{config.Duration(day*30 - time.Second), []string{"v3", "v2", "v1"}},
+		{config.Duration(day * 90), nil},
+		{config.Duration(time.Hour), []string{"v4", "v3", "v2", "v1"}},
+	}
+
+	for _, c := range cases {
+		p := newFactory()(config.C{Policy: config.Policy{AgePolicy: &config.AgePolicy{Age: c.age}}}).(*agePolicyProcessor)
+		p.now = func() time.Time { return now }
+		assert.Nil(t, p.Validate())
+		selected, err := p.Evaluate([]*policy.RepoTags{repo})
+		assert.Nil(t, err)
+
+		var names []string
+		for _, tag := range repo.Tags {
+			if selected.Has(repo, tag.Name) {
+				names = append(names, tag.Name)
+			}
+		}
+		assert.Equal(t, c.expected, names)
+	}
+
+	p := newFactory()(config.C{Policy: config.Policy{AgePolicy: &config.AgePolicy{}}}).(*agePolicyProcessor)
+	assert.NotNil(t, p.Validate())
+}
@@ -0,0 +1,171 @@
+package buckets
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+func init() {
+	policy.RegisterProcessorFactory(policy.BucketsPolicy, newFactory())
+}
+
+func newFactory() func(cfg config.C) policy.Processor {
+	return func(cfg config.C) policy.Processor {
+		return &bucketsPolicyProcessor{
+			BaseProcessor: policy.BaseProcessor{
+				Client: harbor.APIClient,
+				Cfg:    cfg,
+			},
+			now: time.Now,
+		}
+	}
+}
+
+// bucketsPolicyProcessor retains newest tag in each time bucket, tags are ordered by creation time.
+type bucketsPolicyProcessor struct {
+	policy.BaseProcessor
+	now func() time.Time
+}
+
+// level is a bucket level, tags created within the period are put into buckets by key of the
+// creation time, and the newest tag in each bucket is retained.
+type level struct {
+	period time.Duration
+	key    func(t time.Time) string
+}
+
+// Ensure (*bucketsPolicyProcessor) implements interface Processor
+var _ policy.Processor = (*bucketsPolicyProcessor)(nil)
+
+// GetPolicyType gets policy type.
+func (p *bucketsPolicyProcessor) GetPolicyType() policy.Type {
+	return policy.BucketsPolicy
+}
+
+// Validate validates the policy configuration.
+func (p *bucketsPolicyProcessor) Validate() error {
+	cfg := p.Cfg.Policy.BucketsPolicy
+	if cfg == nil {
+		return fmt.Errorf("policy.bucketsPolicy not configured, it's necessary when policy.type == 'buckets'")
+	}
+
+	periods := []struct {
+		name string
+		d    config.Duration
+	}{
+		{"all", cfg.All},
+		{"daily", cfg.Daily},
+		{"weekly", cfg.Weekly},
+		{"monthly", cfg.Monthly},
+		{"yearly", cfg.Yearly},
+	}
+	configured := false
+	for _, period := range periods {
+		if period.d < 0 {
+			return fmt.Errorf("policy.bucketsPolicy.%s should not be negative", period.name)
+		}
+		if period.d > 0 {
+			configured = true
+		}
+	}
+	if !configured {
+		return fmt.Errorf("policy.bucketsPolicy should have at least one of 'all', 'daily', 'weekly', 'monthly' and 'yearly' configured")
+	}
+
+	return nil
+}
+
+// ListCandidates list all candidates to be remove based on the policy
+func (p *bucketsPolicyProcessor) ListCandidates() ([]*policy.Candidate, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	images, err := p.ListTags()
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := p.Evaluate(images)
+	if err != nil {
+		return nil, err
+	}
+
+	return policy.BuildCandidates(images, selected, p.Cfg.Policy.RetainTags), nil
+}
+
+// Evaluate selects tags to remove from the given repos
+func (p *bucketsPolicyProcessor) Evaluate(images []*policy.RepoTags) (policy.Selection, error) {
+	cfg := p.Cfg.Policy.BucketsPolicy
+	levels := p.levels()
+	now := p.now()
+
+	selected := make(policy.Selection)
+	for _, r := range images {
+		tags := make([]policy.Tag, len(r.Tags))
+		copy(tags, r.Tags)
+		sort.SliceStable(tags, func(i, j int) bool {
+			return tags[i].Created.After(tags[j].Created)
+		})
+
+		seen := make([]map[string]bool, len(levels))
+		for i := range seen {
+			seen[i] = make(map[string]bool)
+		}
+
+		for _, t := range tags {
+			age := now.Sub(t.Created)
+			retain := age < cfg.All.Duration()
+			for i, l := range levels {
+				if age >= l.period {
+					continue
+				}
+				key := l.key(t.Created.UTC())
+				if !seen[i][key] {
+					seen[i][key] = true
+					retain = true
+				}
+			}
+
+			if !retain {
+				selected.Add(r, t.Name)
+			}
+		}
+	}
+
+	return selected, nil
+}
+
+// levels gets bucket levels configured, buckets are split by calendar day, ISO week, month and year in UTC.
+func (p *bucketsPolicyProcessor) levels() []level {
+	cfg := p.Cfg.Policy.BucketsPolicy
+	all := []level{
+		{cfg.Daily.Duration(), func(t time.Time) string {
+			return t.Format("2006-01-02")
+		}},
+		{cfg.Weekly.Duration(), func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{cfg.Monthly.Duration(), func(t time.Time) string {
+			return t.Format("2006-01")
+		}},
+		{cfg.Yearly.Duration(), func(t time.Time) string {
+			return t.Format("2006")
+		}},
+	}
+
+	var levels []level
+	for _, l := range all {
+		if l.period > 0 {
+			levels = append(levels, l)
+		}
+	}
+
+	return levels
+}
@@ -0,0 +1,66 @@
+package buckets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2020, 3, 20, 12, 0, 0, 0, time.UTC)
+	day := time.Hour * 24
+	repo := &policy.RepoTags{
+		Project: "library",
+		Repo:    "app",
+		Tags: []policy.Tag{
+			{Name: "d0-b", Created: now.Add(-time.Hour)},
+			{Name: "d0-a", Created: now.Add(-time.Hour * 2)},
+			{Name: "d3-b", Created: now.Add(-day*3 - time.Hour)},
+			{Name: "d3-a", Created: now.Add(-day*3 - time.Hour*2)},
+			{Name: "d10", Created: now.Add(-day * 10)},
+			{Name: "d11", Created: now.Add(-day * 11)},
+			{Name: "d40", Created: now.Add(-day * 40)},
+			{Name: "d41", Created: now.Add(-day * 41)},
+			{Name: "d400", Created: now.Add(-day * 400)},
+		},
+	}
+
+	cases := []struct {
+		policy   config.BucketsPolicy
+		expected []string
+	}{
+		{
+			config.BucketsPolicy{All: config.Duration(day), Daily: config.Duration(day * 7)},
+			[]string{"d3-a", "d10", "d11", "d40", "d41", "d400"},
+		},
+		{
+			config.BucketsPolicy{Weekly: config.Duration(day * 14), Monthly: config.Duration(day * 60)},
+			[]string{"d0-a", "d3-b", "d3-a", "d11", "d41", "d400"},
+		},
+	}
+
+	for _, c := range cases {
+		p := newFactory()(config.C{Policy: config.Policy{BucketsPolicy: &c.policy}}).(*bucketsPolicyProcessor)
+		p.now = func() time.Time { return now }
+		assert.Nil(t, p.Validate())
+		selected, err := p.Evaluate([]*policy.RepoTags{repo})
+		assert.Nil(t, err)
+
+		var names []string
+		for _, tag := range repo.Tags {
+			if selected.Has(repo, tag.Name) {
+				names = append(names, tag.Name)
+			}
+		}
+		assert.Equal(t, c.expected, names)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	p := newFactory()(config.C{Policy: config.Policy{BucketsPolicy: &config.BucketsPolicy{}}}).(*bucketsPolicyProcessor)
+	assert.NotNil(t, p.Validate())
+}
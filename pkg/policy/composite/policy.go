@@ -0,0 +1,183 @@
+package composite
+
+import (
+	"fmt"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+func init() {
+	policy.RegisterProcessorFactory(policy.CompositePolicy, newFactory())
+}
+
+func newFactory() func(cfg config.C) policy.Processor {
+	return func(cfg config.C) policy.Processor {
+		return &compositePolicyProcessor{
+			BaseProcessor: policy.BaseProcessor{
+				Client: harbor.APIClient,
+				Cfg:    cfg,
+			},
+		}
+	}
+}
+
+// compositePolicyProcessor combines other policies with and/or/not. Tags are listed once and each
+// leaf policy evaluates against the same tags, then selections are combined along the rule tree.
+type compositePolicyProcessor struct {
+	policy.BaseProcessor
+	root *node
+}
+
+// node is a compiled rule, leaf node holds the evaluator of a policy.
+type node struct {
+	and       []*node
+	or        []*node
+	not       *node
+	evaluator policy.Evaluator
+}
+
+// Ensure (*compositePolicyProcessor) implements interface Processor
+var _ policy.Processor = (*compositePolicyProcessor)(nil)
+
+// GetPolicyType gets policy type.
+func (p *compositePolicyProcessor) GetPolicyType() policy.Type {
+	return policy.CompositePolicy
+}
+
+// Validate validates the policy configuration, it compiles the rule tree.
+func (p *compositePolicyProcessor) Validate() error {
+	if p.Cfg.Policy.CompositePolicy == nil {
+		return fmt.Errorf("policy.compositePolicy not configured, it's necessary when policy.type == 'composite'")
+	}
+
+	root, err := p.compile(p.Cfg.Policy.CompositePolicy, "policy.compositePolicy")
+	if err != nil {
+		return err
+	}
+	p.root = root
+
+	return nil
+}
+
+// compile compiles the rule into node, 'path' is the location of the rule in config for error message.
+func (p *compositePolicyProcessor) compile(rule *config.Rule, path string) (*node, error) {
+	if rule == nil {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+
+	set := 0
+	for _, ok := range []bool{len(rule.And) > 0, len(rule.Or) > 0, rule.Not != nil, rule.Policy != nil} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("%s should have exactly one of 'and', 'or', 'not' and 'policy'", path)
+	}
+
+	n := &node{}
+	switch {
+	case len(rule.And) > 0:
+		for i, r := range rule.And {
+			child, err := p.compile(r, fmt.Sprintf("%s.and[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			n.and = append(n.and, child)
+		}
+	case len(rule.Or) > 0:
+		for i, r := range rule.Or {
+			child, err := p.compile(r, fmt.Sprintf("%s.or[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			n.or = append(n.or, child)
+		}
+	case rule.Not != nil:
+		child, err := p.compile(rule.Not, path+".not")
+		if err != nil {
+			return nil, err
+		}
+		n.not = child
+	default:
+		// Retain tags of leaf policies are ignored, those of the composite policy apply instead
+		leaf := *rule.Policy
+		leaf.RetainTags = p.Cfg.Policy.RetainTags
+		evaluator, err := policy.NewEvaluator(p.Cfg, leaf)
+		if err != nil {
+			return nil, fmt.Errorf("%s.policy: %v", path, err)
+		}
+		n.evaluator = evaluator
+	}
+
+	return n, nil
+}
+
+// ListCandidates list all candidates to be remove based on the policy
+func (p *compositePolicyProcessor) ListCandidates() ([]*policy.Candidate, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	images, err := p.ListTags()
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := p.Evaluate(images)
+	if err != nil {
+		return nil, err
+	}
+
+	return policy.BuildCandidates(images, selected, p.Cfg.Policy.RetainTags), nil
+}
+
+// Evaluate selects tags to remove from the given repos
+func (p *compositePolicyProcessor) Evaluate(images []*policy.RepoTags) (policy.Selection, error) {
+	if p.root == nil {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.root.evaluate(images)
+}
+
+func (n *node) evaluate(images []*policy.RepoTags) (policy.Selection, error) {
+	switch {
+	case n.evaluator != nil:
+		return n.evaluator.Evaluate(images)
+	case n.not != nil:
+		selected, err := n.not.evaluate(images)
+		if err != nil {
+			return nil, err
+		}
+		return selected.Complement(images), nil
+	case len(n.and) > 0:
+		var result policy.Selection
+		for i, child := range n.and {
+			selected, err := child.evaluate(images)
+			if err != nil {
+				return nil, err
+			}
+			if i == 0 {
+				result = selected
+			} else {
+				result = result.Intersect(images, selected)
+			}
+		}
+		return result, nil
+	default:
+		result := make(policy.Selection)
+		for _, child := range n.or {
+			selected, err := child.evaluate(images)
+			if err != nil {
+				return nil, err
+			}
+			result = result.Union(images, selected)
+		}
+		return result, nil
+	}
+}
@@ -0,0 +1,92 @@
+package composite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+	_ "github.com/cd1989/harbor-cleaner/pkg/policy/age"
+	_ "github.com/cd1989/harbor-cleaner/pkg/policy/number"
+	_ "github.com/cd1989/harbor-cleaner/pkg/policy/regex"
+)
+
+const testPolicy = `
+type: composite
+compositePolicy:
+  or:
+  - and:
+    - policy:
+        type: regex
+        regexPolicy:
+          repos: [".*"]
+          tags: ["pr-.*"]
+    - policy:
+        type: age
+        agePolicy:
+          age: 14d
+  - and:
+    - policy:
+        type: number
+        numberPolicy:
+          number: 3
+    - not:
+        policy:
+          type: regex
+          regexPolicy:
+            repos: [".*"]
+            tags: ["v.*"]
+retainTags: ["pr-1"]
+`
+
+func TestComposite(t *testing.T) {
+	cfg := config.C{}
+	assert.Nil(t, yaml.Unmarshal([]byte(testPolicy), &cfg.Policy))
+
+	day := time.Hour * 24
+	now := time.Now()
+	repos := []*policy.RepoTags{
+		{
+			Project: "library",
+			Repo:    "app",
+			Tags: []policy.Tag{
+				{Name: "pr-3", Digest: "d1", Created: now.Add(-day)},
+				{Name: "v1.2", Digest: "d2", Created: now.Add(-day * 2)},
+				{Name: "pr-2", Digest: "d3", Created: now.Add(-day * 20)},
+				{Name: "pr-1", Digest: "d4", Created: now.Add(-day * 30)},
+				{Name: "v1.1", Digest: "d5", Created: now.Add(-day * 40)},
+				{Name: "dev", Digest: "d2", Created: now.Add(-day * 50)},
+			},
+		},
+	}
+
+	processor := newFactory()(cfg).(*compositePolicyProcessor)
+	assert.Nil(t, processor.Validate())
+	selected, err := processor.Evaluate(repos)
+	assert.Nil(t, err)
+
+	candidates := policy.BuildCandidates(repos, selected, cfg.Policy.RetainTags)
+	assert.Equal(t, 1, len(candidates))
+	var names []string
+	for _, tag := range candidates[0].Tags {
+		names = append(names, tag.Name)
+	}
+	assert.Equal(t, []string{"pr-2", "dev"}, names)
+	assert.Equal(t, map[string][]string{"d2": {"v1.2"}}, candidates[0].Protected)
+}
+
+func TestInvalidRule(t *testing.T) {
+	cfg := config.C{Policy: config.Policy{
+		Type: "composite",
+		CompositePolicy: &config.Rule{
+			And: []*config.Rule{{Not: &config.Rule{}}},
+		},
+	}}
+	assert.NotNil(t, newFactory()(cfg).(*compositePolicyProcessor).Validate())
+
+	cfg.Policy.CompositePolicy = &config.Rule{Policy: &config.Policy{Type: "unknown"}}
+	assert.NotNil(t, newFactory()(cfg).(*compositePolicyProcessor).Validate())
+}
@@ -0,0 +1,185 @@
+package expiry
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+const (
+	// ExpiresAfterLabel is image label of the lifetime since image creation, e.g. '30d'
+	ExpiresAfterLabel = "harbor-cleaner.expires-after"
+	// ExpiresAtLabel is image label of the expiry time, e.g. '2026-12-31' or '2026-12-31T08:00:00Z', a
+	// date expires at the end of the day in UTC
+	ExpiresAtLabel = "harbor-cleaner.expires-at"
+)
+
+func init() {
+	policy.RegisterProcessorFactory(policy.ExpiryPolicy, newFactory())
+}
+
+func newFactory() func(cfg config.C) policy.Processor {
+	return func(cfg config.C) policy.Processor {
+		return &expiryPolicyProcessor{
+			BaseProcessor: policy.BaseProcessor{
+				Client: harbor.APIClient,
+				Cfg:    cfg,
+			},
+			now: time.Now,
+		}
+	}
+}
+
+// expiryPolicyProcessor cleans images expired according to their expiry labels. If both labels
+// present, the earlier expiry wins. Images without the labels are evaluated by the default policy,
+// or kept if it's not configured. Images with invalid labels are kept.
+type expiryPolicyProcessor struct {
+	policy.BaseProcessor
+	now       func() time.Time
+	fallback  policy.Evaluator
+	validated bool
+}
+
+// Ensure (*expiryPolicyProcessor) implements interface Processor
+var _ policy.Processor = (*expiryPolicyProcessor)(nil)
+
+// GetPolicyType gets policy type.
+func (p *expiryPolicyProcessor) GetPolicyType() policy.Type {
+	return policy.ExpiryPolicy
+}
+
+// Validate validates the policy configuration, it creates evaluator of the default policy.
+func (p *expiryPolicyProcessor) Validate() error {
+	cfg := p.Cfg.Policy.ExpiryPolicy
+	if cfg == nil {
+		return fmt.Errorf("policy.expiryPolicy not configured, it's necessary when policy.type == 'expiry'")
+	}
+
+	p.fallback = nil
+	if cfg.Default != nil {
+		// Retain tags of the default policy are ignored, those of the expiry policy apply instead
+		fallback := *cfg.Default
+		fallback.RetainTags = p.Cfg.Policy.RetainTags
+		e, err := policy.NewEvaluator(p.Cfg, fallback)
+		if err != nil {
+			return fmt.Errorf("policy.expiryPolicy.default: %v", err)
+		}
+		p.fallback = e
+	}
+	p.validated = true
+
+	return nil
+}
+
+// ListCandidates list all candidates to be remove based on the policy
+func (p *expiryPolicyProcessor) ListCandidates() ([]*policy.Candidate, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	images, err := p.ListTags()
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := p.Evaluate(images)
+	if err != nil {
+		return nil, err
+	}
+
+	return policy.BuildCandidates(images, selected, p.Cfg.Policy.RetainTags), nil
+}
+
+// Evaluate selects tags to remove from the given repos
+func (p *expiryPolicyProcessor) Evaluate(images []*policy.RepoTags) (policy.Selection, error) {
+	if !p.validated {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	now := p.now()
+	selected := make(policy.Selection)
+	var unlabeled []*policy.RepoTags
+	for _, r := range images {
+		var rest []policy.Tag
+		for _, t := range r.Tags {
+			expiry, labeled, err := expiryOf(t)
+			if err != nil {
+				logrus.Warningf("Invalid expiry label of %s/%s:%s, keep it: %v", r.Project, r.Repo, t.Name, err)
+				continue
+			}
+			if !labeled {
+				rest = append(rest, t)
+				continue
+			}
+			if now.After(expiry) {
+				selected.Add(r, t.Name)
+			}
+		}
+		if len(rest) > 0 {
+			unlabeled = append(unlabeled, &policy.RepoTags{Project: r.Project, Repo: r.Repo, Tags: rest})
+		}
+	}
+
+	if p.fallback == nil || len(unlabeled) == 0 {
+		return selected, nil
+	}
+
+	fallback, err := p.fallback.Evaluate(unlabeled)
+	if err != nil {
+		return nil, fmt.Errorf("evaluate default policy error: %v", err)
+	}
+	for _, r := range unlabeled {
+		for _, t := range r.Tags {
+			if fallback.Has(r, t.Name) {
+				selected.Add(r, t.Name)
+			}
+		}
+	}
+
+	return selected, nil
+}
+
+// expiryOf gets expiry time of the image from its labels, 'labeled' is false if neither label present.
+func expiryOf(t policy.Tag) (expiry time.Time, labeled bool, err error) {
+	if v, ok := t.Labels[ExpiresAfterLabel]; ok {
+		d, err := config.ParseDuration(v)
+		if err != nil {
+			return expiry, true, fmt.Errorf("%s: %v", ExpiresAfterLabel, err)
+		}
+		expiry, labeled = t.Created.Add(d.Duration()), true
+	}
+
+	if v, ok := t.Labels[ExpiresAtLabel]; ok {
+		at, err := parseTime(v)
+		if err != nil {
+			return expiry, true, fmt.Errorf("%s: %v", ExpiresAtLabel, err)
+		}
+		if !labeled || at.Before(expiry) {
+			expiry = at
+		}
+		labeled = true
+	}
+
+	return expiry, labeled, nil
+}
+
+// parseTime parses a date like '2026-12-31' or RFC3339 time. A date means the end of that day in UTC,
+// i.e. the image is kept through the whole day and expires at midnight of the next day.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t.AddDate(0, 0, 1), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("invalid time '%s', e.g. '2026-12-31' or '2026-12-31T08:00:00Z' expected", s)
+	}
+	return t, nil
+}
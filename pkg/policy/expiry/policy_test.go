@@ -0,0 +1,80 @@
+package expiry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+	_ "github.com/cd1989/harbor-cleaner/pkg/policy/age"
+)
+
+func TestEvaluate(t *testing.T) {
+	day := time.Hour * 24
+	now := time.Now()
+	repo := &policy.RepoTags{
+		Project: "library",
+		Repo:    "app",
+		Tags: []policy.Tag{
+			{Name: "v7", Created: now.Add(-day * 10), Labels: map[string]string{ExpiresAtLabel: now.UTC().Format("2006-01-02")}},
+			{Name: "v6", Created: now.Add(-day * 10), Labels: map[string]string{ExpiresAfterLabel: "30d"}},
+			{Name: "v5", Created: now.Add(-day * 40), Labels: map[string]string{ExpiresAfterLabel: "30d"}},
+			{Name: "v4", Created: now.Add(-day * 10), Labels: map[string]string{ExpiresAtLabel: now.Add(-day * 2).Format("2006-01-02")}},
+			{Name: "v3", Created: now.Add(-day * 10), Labels: map[string]string{ExpiresAfterLabel: "1w", ExpiresAtLabel: now.Add(day * 200).Format(time.RFC3339)}},
+			{Name: "v2", Created: now.Add(-day * 40), Labels: map[string]string{ExpiresAfterLabel: "forever"}},
+			{Name: "v1", Created: now.Add(-day * 40)},
+			{Name: "v0", Created: now.Add(-day * 10)},
+		},
+	}
+
+	cases := []struct {
+		policy   config.ExpiryPolicy
+		expected []string
+	}{
+		{
+			config.ExpiryPolicy{},
+			[]string{"v5", "v4", "v3"},
+		},
+		{
+			config.ExpiryPolicy{Default: &config.Policy{Type: "age", AgePolicy: &config.AgePolicy{Age: config.Duration(day * 30)}}},
+			[]string{"v5", "v4", "v3", "v1"},
+		},
+	}
+
+	for _, c := range cases {
+		p := newFactory()(config.C{Policy: config.Policy{ExpiryPolicy: &c.policy}}).(*expiryPolicyProcessor)
+		p.now = func() time.Time { return now }
+		assert.Nil(t, p.Validate())
+		selected, err := p.Evaluate([]*policy.RepoTags{repo})
+		assert.Nil(t, err)
+
+		var names []string
+		for _, tag := range repo.Tags {
+			if selected.Has(repo, tag.Name) {
+				names = append(names, tag.Name)
+			}
+		}
+		assert.Equal(t, c.expected, names)
+	}
+
+	p := newFactory()(config.C{Policy: config.Policy{ExpiryPolicy: &config.ExpiryPolicy{
+		Default: &config.Policy{Type: "unknown"},
+	}}}).(*expiryPolicyProcessor)
+	assert.NotNil(t, p.Validate())
+}
+
+func TestParseTime(t *testing.T) {
+	// A date expires at the end of the day
+	at, err := parseTime("2026-12-31")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), at)
+
+	at, err = parseTime("2026-12-31T08:00:00+08:00")
+	assert.Nil(t, err)
+	assert.True(t, at.Equal(time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)))
+
+	_, err = parseTime("31/12/2026")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,101 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+)
+
+// filteredEvaluator evaluates a policy only on tags whose image metadata match the filters, tags not
+// matched are invisible to the policy, the same as tags not matched by label selector.
+type filteredEvaluator struct {
+	filters   *config.Filters
+	evaluator Evaluator
+}
+
+// Evaluate selects tags to remove from the given repos.
+func (e *filteredEvaluator) Evaluate(repos []*RepoTags) (Selection, error) {
+	return e.evaluator.Evaluate(MatchFilters(repos, e.filters))
+}
+
+// ValidateFilters validates the filters.
+func ValidateFilters(f *config.Filters) error {
+	if len(f.DockerVersionBefore) > 0 {
+		if _, ok := parseVersion(f.DockerVersionBefore); !ok {
+			return fmt.Errorf("invalid dockerVersionBefore '%s' in filters, e.g. '18.09' expected", f.DockerVersionBefore)
+		}
+	}
+
+	return nil
+}
+
+// MatchFilters filters tags of the repos by the filters, repos without tags matched are dropped.
+func MatchFilters(repos []*RepoTags, f *config.Filters) []*RepoTags {
+	return filterTags(repos, func(t Tag) bool {
+		return matchField(f.Architectures, t.Architecture) &&
+			matchField(f.OS, t.OS) &&
+			matchField(f.Authors, t.Author) &&
+			matchField(f.DockerVersions, t.DockerVersion) &&
+			versionBefore(t.DockerVersion, f.DockerVersionBefore)
+	})
+}
+
+// matchField checks whether the value matches any of the patterns, it's true if no patterns.
+func matchField(patterns []string, value string) bool {
+	return len(patterns) == 0 || MatchAny(patterns, value)
+}
+
+// versionBefore checks whether the version is earlier than the bound, it's true if no bound. Images
+// without docker version, e.g. built by other tools, never match a bound.
+func versionBefore(version, bound string) bool {
+	if len(bound) == 0 {
+		return true
+	}
+
+	v, ok := parseVersion(version)
+	if !ok {
+		return false
+	}
+	b, _ := parseVersion(bound)
+	for i := 0; i < len(v) || i < len(b); i++ {
+		var x, y int
+		if i < len(v) {
+			x = v[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return x < y
+		}
+	}
+
+	return false
+}
+
+// parseVersion parses numeric parts of a docker version like '18.09.7' or '17.06.0-ce', parsing stops
+// at the first part not starting with a number.
+func parseVersion(s string) ([]int, bool) {
+	var parts []int
+	for _, part := range strings.Split(strings.TrimPrefix(s, "v"), ".") {
+		i := 0
+		for i < len(part) && part[i] >= '0' && part[i] <= '9' {
+			i++
+		}
+		if i == 0 {
+			break
+		}
+		n, err := strconv.Atoi(part[:i])
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+		if i < len(part) {
+			break
+		}
+	}
+
+	return parts, len(parts) > 0
+}
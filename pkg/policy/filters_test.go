@@ -0,0 +1,51 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+)
+
+func TestFilteredEvaluator(t *testing.T) {
+	repo := &RepoTags{
+		Project: "library",
+		Repo:    "app",
+		Tags: []Tag{
+			{Name: "v1", OS: "linux", Architecture: "amd64", Author: "ci", DockerVersion: "18.09.7"},
+			{Name: "v2", OS: "linux", Architecture: "arm64", Author: "ci", DockerVersion: "17.06.0-ce"},
+			{Name: "v3", OS: "windows", Architecture: "amd64", Author: "legacy-ci", DockerVersion: "1.13.1"},
+			{Name: "v4", OS: "linux", Architecture: "amd64"},
+		},
+	}
+
+	cases := []struct {
+		filters  config.Filters
+		expected []string
+	}{
+		{config.Filters{OS: []string{"windows"}}, []string{"v3"}},
+		{config.Filters{Architectures: []string{"arm*", "amd64"}, Authors: []string{"ci"}}, []string{"v1", "v2"}},
+		{config.Filters{DockerVersions: []string{"1.*", "17.*"}}, []string{"v2", "v3"}},
+		{config.Filters{DockerVersionBefore: "18.09"}, []string{"v2", "v3"}},
+		{config.Filters{DockerVersionBefore: "18.09.8", OS: []string{"linux"}}, []string{"v1", "v2"}},
+		{config.Filters{}, []string{"v1", "v2", "v3", "v4"}},
+	}
+
+	for _, c := range cases {
+		assert.Nil(t, ValidateFilters(&c.filters))
+		e := &filteredEvaluator{filters: &c.filters, evaluator: allEvaluator{}}
+		selected, err := e.Evaluate([]*RepoTags{repo})
+		assert.Nil(t, err)
+
+		var names []string
+		for _, tag := range repo.Tags {
+			if selected.Has(repo, tag.Name) {
+				names = append(names, tag.Name)
+			}
+		}
+		assert.Equal(t, c.expected, names)
+	}
+
+	assert.NotNil(t, ValidateFilters(&config.Filters{DockerVersionBefore: "latest"}))
+}
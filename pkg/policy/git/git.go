@@ -0,0 +1,139 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// mergePatterns match branch names in default messages of merge commits by git, GitLab and GitHub.
+var mergePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^Merge branch '([^']+)'`),
+	regexp.MustCompile(`^Merge remote-tracking branch '[^/']+/([^']+)'`),
+	regexp.MustCompile(`^Merge pull request #[0-9]+ from [^/\s]+/(\S+)`),
+}
+
+// checkoutPattern matches branches switched between in reflog.
+var checkoutPattern = regexp.MustCompile(`^checkout: moving from (\S+) to (\S+)$`)
+
+// repository is a local git repository accessed by the git command.
+type repository struct {
+	path   string
+	remote string
+}
+
+// run runs a git command in the repository and returns non-empty lines of the output.
+func (r *repository) run(args ...string) ([]string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", r.path}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s error: %v, %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	var lines []string
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// branches lists branch names, remote branches are listed without the remote prefix.
+func (r *repository) branches() ([]string, error) {
+	prefix := "refs/heads/"
+	if len(r.remote) > 0 {
+		prefix = "refs/remotes/" + r.remote + "/"
+	}
+
+	refs, err := r.run("for-each-ref", "--format=%(refname)", prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []string
+	for _, ref := range refs {
+		name := strings.TrimPrefix(ref, prefix)
+		if name != "HEAD" {
+			branches = append(branches, name)
+		}
+	}
+	return branches, nil
+}
+
+// refs gets arguments of all branches and tags for commands like 'rev-list' and 'log'.
+func (r *repository) refs() []string {
+	if len(r.remote) > 0 {
+		return []string{"--tags", "--remotes=" + r.remote}
+	}
+	return []string{"--tags", "--branches"}
+}
+
+// commits lists sorted full hashes of commits reachable from any branch or tag.
+func (r *repository) commits() ([]string, error) {
+	commits, err := r.run(append([]string{"rev-list"}, r.refs()...)...)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(commits)
+	return commits, nil
+}
+
+// pastBranches lists names of branches the repository has had, including deleted ones, as far as
+// the history tells. They're found in messages of merge commits, and in checkouts recorded in reflog
+// of local clones. Reflog is optional, it's missing in fresh and bare clones.
+func (r *repository) pastBranches() (map[string]bool, error) {
+	messages, err := r.run(append([]string{"log", "--merges", "--format=%s"}, r.refs()...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	branches := make(map[string]bool)
+	for _, msg := range messages {
+		for _, pattern := range mergePatterns {
+			if m := pattern.FindStringSubmatch(msg); m != nil {
+				branches[m[1]] = true
+				break
+			}
+		}
+	}
+
+	if reflog, err := r.run("reflog", "--format=%gs"); err == nil {
+		for _, entry := range reflog {
+			if m := checkoutPattern.FindStringSubmatch(entry); m != nil {
+				branches[m[1]] = true
+				branches[m[2]] = true
+			}
+		}
+	}
+
+	return branches, nil
+}
+
+// isShallow checks whether the repository is a shallow clone, history of which is incomplete.
+func (r *repository) isShallow() (bool, error) {
+	lines, err := r.run("rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return false, err
+	}
+	return len(lines) > 0 && lines[0] == "true", nil
+}
+
+// hasCommit checks whether the commit, in full or abbreviated hash, exists in the repository.
+// Commits not fetched yet, and abbreviated hashes that are ambiguous, are regarded as not exist.
+func (r *repository) hasCommit(commit string) bool {
+	_, err := r.run("cat-file", "-e", commit+"^{commit}")
+	return err == nil
+}
+
+// containsCommit checks whether a commit, in full or abbreviated hash, is in the sorted hashes.
+func containsCommit(commits []string, commit string) bool {
+	commit = strings.ToLower(commit)
+	i := sort.SearchStrings(commits, commit)
+	return i < len(commits) && strings.HasPrefix(commits[i], commit)
+}
@@ -0,0 +1,266 @@
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+const (
+	// DefaultRevisionLabel is the OCI image label of the commit the image is built from
+	DefaultRevisionLabel = "org.opencontainers.image.revision"
+	// DefaultGracePeriod is the default period to keep images built recently
+	DefaultGracePeriod = config.Duration(time.Hour * 24)
+
+	branchPlaceholder = "{branch}"
+	commitPlaceholder = "{commit}"
+)
+
+// commitPattern matches full or abbreviated commit hash.
+var commitPattern = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
+// invalidTagChars are characters not allowed in image tags, CI usually replaces them in branch names with '-'.
+var invalidTagChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// invalidPatternChars are characters in branch patterns to sanitize, wildcards '*' and '?' are kept.
+var invalidPatternChars = regexp.MustCompile(`[^A-Za-z0-9_.*?-]`)
+
+func init() {
+	policy.RegisterProcessorFactory(policy.GitPolicy, newFactory())
+}
+
+func newFactory() func(cfg config.C) policy.Processor {
+	return func(cfg config.C) policy.Processor {
+		return &gitPolicyProcessor{
+			BaseProcessor: policy.BaseProcessor{
+				Client: harbor.APIClient,
+				Cfg:    cfg,
+			},
+			now: time.Now,
+		}
+	}
+}
+
+// gitPolicyProcessor cleans images whose branch has been deleted, or whose commit is no longer
+// reachable from any branch or tag in the git repository. Branch and commit are parsed from tag
+// name by the template, commit is also taken from the revision label of the image. A branch is
+// regarded as deleted only if the history shows the repository had it. Images of protected
+// branches, images with neither branch nor commit known, images of commits not in the repository,
+// and images built within the grace period are kept. Shallow clones are refused, as
+// commits beyond the depth would be regarded as unreachable.
+type gitPolicyProcessor struct {
+	policy.BaseProcessor
+	pattern *regexp.Regexp
+	now     func() time.Time
+}
+
+// Ensure (*gitPolicyProcessor) implements interface Processor
+var _ policy.Processor = (*gitPolicyProcessor)(nil)
+
+// GetPolicyType gets policy type.
+func (p *gitPolicyProcessor) GetPolicyType() policy.Type {
+	return policy.GitPolicy
+}
+
+// Validate validates the policy configuration, it compiles the tag template.
+func (p *gitPolicyProcessor) Validate() error {
+	cfg := p.Cfg.Policy.GitPolicy
+	if cfg == nil {
+		return fmt.Errorf("policy.gitPolicy not configured, it's necessary when policy.type == 'git'")
+	}
+
+	if len(cfg.Repo) == 0 {
+		return fmt.Errorf("policy.gitPolicy.repo should not be empty")
+	}
+
+	if cfg.GracePeriod < 0 {
+		return fmt.Errorf("policy.gitPolicy.gracePeriod should not be negative")
+	}
+
+	if len(cfg.TagTemplate) > 0 {
+		pattern, err := compileTemplate(cfg.TagTemplate)
+		if err != nil {
+			return fmt.Errorf("invalid policy.gitPolicy.tagTemplate: %v", err)
+		}
+		p.pattern = pattern
+	}
+
+	return nil
+}
+
+// compileTemplate compiles tag template to regex with named captures 'branch' and 'commit'.
+func compileTemplate(template string) (*regexp.Regexp, error) {
+	branches, commits := strings.Count(template, branchPlaceholder), strings.Count(template, commitPlaceholder)
+	if branches > 1 || commits > 1 || branches+commits == 0 {
+		return nil, fmt.Errorf("'%s' should contain '%s' or '%s' or both, each at most once", template, branchPlaceholder, commitPlaceholder)
+	}
+
+	expr := regexp.QuoteMeta(template)
+	expr = strings.Replace(expr, regexp.QuoteMeta(branchPlaceholder), `(?P<branch>.+)`, 1)
+	expr = strings.Replace(expr, regexp.QuoteMeta(commitPlaceholder), `(?P<commit>[0-9a-fA-F]{7,40})`, 1)
+	return regexp.Compile("^" + expr + "$")
+}
+
+// ListCandidates list all candidates to be remove based on the policy
+func (p *gitPolicyProcessor) ListCandidates() ([]*policy.Candidate, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	images, err := p.ListTags()
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := p.Evaluate(images)
+	if err != nil {
+		return nil, err
+	}
+
+	return policy.BuildCandidates(images, selected, p.Cfg.Policy.RetainTags), nil
+}
+
+// Evaluate selects tags to remove from the given repos
+func (p *gitPolicyProcessor) Evaluate(images []*policy.RepoTags) (policy.Selection, error) {
+	cfg := p.Cfg.Policy.GitPolicy
+	repo := &repository{path: cfg.Repo, remote: cfg.Remote}
+
+	shallow, err := repo.isShallow()
+	if err != nil {
+		return nil, err
+	}
+	if shallow {
+		return nil, fmt.Errorf("git repo '%s' is a shallow clone, fetch full history with 'git fetch --unshallow'", cfg.Repo)
+	}
+
+	names, err := repo.branches()
+	if err != nil {
+		return nil, err
+	}
+	// No branches usually means a wrong repo or remote, images of all branches would be removed.
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no branches found in git repo '%s', check policy.gitPolicy.repo and policy.gitPolicy.remote", cfg.Repo)
+	}
+	// Branches by names in tags, both raw and sanitized, mapped to raw names
+	branches := make(map[string][]string)
+	for _, name := range names {
+		branches[name] = append(branches[name], name)
+		if sanitized := invalidTagChars.ReplaceAllString(name, "-"); sanitized != name {
+			branches[sanitized] = append(branches[sanitized], name)
+		}
+	}
+
+	// Deleted branches known from the history, names not in it (e.g. 'v1.2' matching '{branch}')
+	// may not be branches at all.
+	past, err := repo.pastBranches()
+	if err != nil {
+		return nil, err
+	}
+	deleted := make(map[string]bool)
+	for name := range past {
+		deleted[name] = true
+		deleted[invalidTagChars.ReplaceAllString(name, "-")] = true
+	}
+
+	commits, err := repo.commits()
+	if err != nil {
+		return nil, err
+	}
+
+	grace := cfg.GracePeriod
+	if grace == 0 {
+		grace = DefaultGracePeriod
+	}
+	deadline := p.now().Add(-grace.Duration())
+
+	// Whether commits not reachable exist in the repo, commits not fetched yet are kept.
+	known := make(map[string]bool)
+	selected := make(policy.Selection)
+	for _, r := range images {
+		for _, t := range r.Tags {
+			if t.Created.After(deadline) {
+				continue
+			}
+
+			branch, commit := p.parse(t)
+			if len(branch) > 0 {
+				if p.protected(branch, branches[branch]) {
+					continue
+				}
+				if len(branches[branch]) == 0 && deleted[branch] {
+					selected.Add(r, t.Name)
+					continue
+				}
+			}
+
+			if len(commit) == 0 || containsCommit(commits, commit) {
+				continue
+			}
+			exists, ok := known[commit]
+			if !ok {
+				exists = repo.hasCommit(commit)
+				known[commit] = exists
+			}
+			if exists {
+				selected.Add(r, t.Name)
+			}
+		}
+	}
+
+	return selected, nil
+}
+
+// protected checks whether the branch in tag name is protected. Patterns are matched against the
+// name in tag and the raw names of the branch, and in case the branch is deleted, patterns are also
+// sanitized the same way as branch names in tags, e.g. 'release/*' matches 'release-1.0'.
+func (p *gitPolicyProcessor) protected(branch string, raw []string) bool {
+	patterns := p.Cfg.Policy.GitPolicy.ProtectedBranches
+	if policy.MatchAny(patterns, branch) {
+		return true
+	}
+	for _, name := range raw {
+		if policy.MatchAny(patterns, name) {
+			return true
+		}
+	}
+	for _, pattern := range patterns {
+		if policy.MatchGlob(invalidPatternChars.ReplaceAllString(pattern, "-"), branch) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parse gets branch and commit of the image, commit in the tag name takes precedence over the label.
+func (p *gitPolicyProcessor) parse(t policy.Tag) (branch, commit string) {
+	if p.pattern != nil {
+		if m := p.pattern.FindStringSubmatch(t.Name); m != nil {
+			for i, name := range p.pattern.SubexpNames() {
+				switch name {
+				case "branch":
+					branch = m[i]
+				case "commit":
+					commit = m[i]
+				}
+			}
+		}
+	}
+
+	if len(commit) == 0 {
+		label := p.Cfg.Policy.GitPolicy.RevisionLabel
+		if len(label) == 0 {
+			label = DefaultRevisionLabel
+		}
+		if revision := t.Labels[label]; commitPattern.MatchString(revision) {
+			commit = revision
+		}
+	}
+
+	return branch, commit
+}
@@ -0,0 +1,177 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+// gitRepo creates a git repo with branches 'master' and 'feature/login', a commit only reachable
+// from deleted branch 'feature/old', and branch 'feature/merged' deleted after merged to master. It
+// returns path of the repo and hashes of the commits.
+func gitRepo(t *testing.T) (string, map[string]string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	dir, err := ioutil.TempDir("", "gitpolicy")
+	assert.Nil(t, err)
+
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v error: %v, %s", args, err, out)
+		}
+		return string(out)
+	}
+	head := func() string {
+		lines, err := (&repository{path: dir}).run("rev-parse", "HEAD")
+		assert.Nil(t, err)
+		return lines[0]
+	}
+
+	commits := make(map[string]string)
+	git("init", "-q")
+	git("checkout", "-q", "-b", "master")
+	git("commit", "-q", "--allow-empty", "-m", "init")
+	commits["master"] = head()
+	git("checkout", "-q", "-b", "feature/login")
+	git("commit", "-q", "--allow-empty", "-m", "login")
+	commits["login"] = head()
+	git("checkout", "-q", "-b", "feature/old", "master")
+	git("commit", "-q", "--allow-empty", "-m", "old")
+	commits["old"] = head()
+	git("checkout", "-q", "master")
+	git("branch", "-q", "-D", "feature/old")
+	git("checkout", "-q", "-b", "feature/merged")
+	git("commit", "-q", "--allow-empty", "-m", "merged")
+	git("checkout", "-q", "master")
+	git("merge", "-q", "--no-ff", "--no-edit", "feature/merged")
+	git("branch", "-q", "-D", "feature/merged")
+
+	return dir, commits
+}
+
+func TestEvaluate(t *testing.T) {
+	dir, commits := gitRepo(t)
+	defer os.RemoveAll(dir)
+
+	repo := &policy.RepoTags{
+		Project: "ci",
+		Repo:    "app",
+		Tags: []policy.Tag{
+			{Name: "master-" + commits["master"][:7]},
+			{Name: "feature-login-" + commits["login"][:8]},
+			{Name: "feature-old-" + commits["old"][:7]},
+			{Name: "master-" + commits["old"][:7]},
+			{Name: "release-1.0-" + commits["old"][:7]},
+			{Name: "latest", Labels: map[string]string{DefaultRevisionLabel: commits["old"]}},
+			{Name: "stable", Labels: map[string]string{DefaultRevisionLabel: commits["master"]}},
+			{Name: "dev"},
+			{Name: "master-deadbee"},
+			{Name: "hotfix-1.0-" + commits["old"][:7]},
+			{Name: "feature-new-" + commits["old"][:7], Created: time.Now()},
+		},
+	}
+
+	cfg := config.C{Policy: config.Policy{GitPolicy: &config.GitPolicy{
+		Repo:              dir,
+		TagTemplate:       "{branch}-{commit}",
+		ProtectedBranches: []string{"release-*", "hotfix/*"},
+	}}}
+	p := newFactory()(cfg).(*gitPolicyProcessor)
+	assert.Nil(t, p.Validate())
+	selected, err := p.Evaluate([]*policy.RepoTags{repo})
+	assert.Nil(t, err)
+
+	var names []string
+	for _, tag := range repo.Tags {
+		if selected.Has(repo, tag.Name) {
+			names = append(names, tag.Name)
+		}
+	}
+	assert.Equal(t, []string{"feature-old-" + commits["old"][:7], "master-" + commits["old"][:7], "latest"}, names)
+}
+
+func TestBranchTemplate(t *testing.T) {
+	dir, _ := gitRepo(t)
+	defer os.RemoveAll(dir)
+
+	clone := filepath.Join(dir, "clone")
+	out, err := exec.Command("git", "clone", "-q", "file://"+dir, clone).CombinedOutput()
+	assert.Nil(t, err, string(out))
+
+	cases := []struct {
+		repo     string
+		remote   string
+		expected []string
+	}{
+		// Deleted branches known from reflog and merge commits
+		{dir, "", []string{"feature-old", "feature-merged"}},
+		// Fresh clone has no reflog, only merged branches are known
+		{clone, "origin", []string{"feature-merged"}},
+	}
+	for _, c := range cases {
+		repo := &policy.RepoTags{
+			Project: "ci",
+			Repo:    "app",
+			Tags: []policy.Tag{
+				{Name: "master"},
+				{Name: "feature-login"},
+				{Name: "feature-old"},
+				{Name: "feature-merged"},
+				{Name: "v1.2"},
+				{Name: "latest"},
+			},
+		}
+
+		cfg := config.C{Policy: config.Policy{GitPolicy: &config.GitPolicy{Repo: c.repo, Remote: c.remote, TagTemplate: "{branch}"}}}
+		p := newFactory()(cfg).(*gitPolicyProcessor)
+		assert.Nil(t, p.Validate())
+		selected, err := p.Evaluate([]*policy.RepoTags{repo})
+		assert.Nil(t, err)
+
+		var names []string
+		for _, tag := range repo.Tags {
+			if selected.Has(repo, tag.Name) {
+				names = append(names, tag.Name)
+			}
+		}
+		assert.Equal(t, c.expected, names, c.repo)
+	}
+}
+
+func TestShallowRepo(t *testing.T) {
+	dir, _ := gitRepo(t)
+	defer os.RemoveAll(dir)
+
+	shallow := filepath.Join(dir, "shallow")
+	out, err := exec.Command("git", "clone", "-q", "--depth", "1", "file://"+dir, shallow).CombinedOutput()
+	assert.Nil(t, err, string(out))
+
+	cfg := config.C{Policy: config.Policy{GitPolicy: &config.GitPolicy{Repo: shallow, TagTemplate: "{branch}-{commit}"}}}
+	p := newFactory()(cfg).(*gitPolicyProcessor)
+	assert.Nil(t, p.Validate())
+	_, err = p.Evaluate(nil)
+	assert.NotNil(t, err)
+}
+
+func TestCompileTemplate(t *testing.T) {
+	for _, template := range []string{"{branch}-{commit}", "pr.{commit}", "{branch}"} {
+		_, err := compileTemplate(template)
+		assert.Nil(t, err, template)
+	}
+	for _, template := range []string{"v1", "{commit}-{commit}"} {
+		_, err := compileTemplate(template)
+		assert.NotNil(t, err, template)
+	}
+}
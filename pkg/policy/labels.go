@@ -0,0 +1,55 @@
+package policy
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ParseLabelSelector parses a Kubernetes style label selector, equality-based ('team=data',
+// 'retention!=permanent'), set-based ('env in (dev,test)', 'env notin (prod)') and existence
+// ('temporary', '!permanent') requirements are supported, separated by commas.
+func ParseLabelSelector(selector string) (labels.Selector, error) {
+	s, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector '%s': %v", selector, err)
+	}
+	return s, nil
+}
+
+// labelScopedEvaluator evaluates a policy only on tags whose image labels match the selector, tags
+// not matched are invisible to the policy, so they are neither selected nor counted, e.g. by number.
+type labelScopedEvaluator struct {
+	selector  labels.Selector
+	evaluator Evaluator
+}
+
+// Evaluate selects tags to remove from the given repos.
+func (e *labelScopedEvaluator) Evaluate(repos []*RepoTags) (Selection, error) {
+	return e.evaluator.Evaluate(MatchLabels(repos, e.selector))
+}
+
+// MatchLabels filters tags of the repos by the label selector, repos without tags matched are dropped.
+func MatchLabels(repos []*RepoTags, selector labels.Selector) []*RepoTags {
+	return filterTags(repos, func(t Tag) bool {
+		return selector.Matches(labels.Set(t.Labels))
+	})
+}
+
+// filterTags filters tags of the repos, repos without tags matched are dropped.
+func filterTags(repos []*RepoTags, match func(t Tag) bool) []*RepoTags {
+	var results []*RepoTags
+	for _, r := range repos {
+		var tags []Tag
+		for _, t := range r.Tags {
+			if match(t) {
+				tags = append(tags, t)
+			}
+		}
+		if len(tags) > 0 {
+			results = append(results, &RepoTags{Project: r.Project, Repo: r.Repo, Tags: tags})
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,74 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// allEvaluator selects all tags it sees.
+type allEvaluator struct{}
+
+func (allEvaluator) Evaluate(repos []*RepoTags) (Selection, error) {
+	selected := make(Selection)
+	for _, r := range repos {
+		for _, t := range r.Tags {
+			selected.Add(r, t.Name)
+		}
+	}
+	return selected, nil
+}
+
+func TestLabelScopedEvaluator(t *testing.T) {
+	repos := []*RepoTags{
+		{
+			Project: "library",
+			Repo:    "app",
+			Tags: []Tag{
+				{Name: "v1", Labels: map[string]string{"team": "data"}},
+				{Name: "v2", Labels: map[string]string{"team": "data", "retention": "permanent"}},
+				{Name: "v3", Labels: map[string]string{"team": "web", "temporary": ""}},
+				{Name: "v4"},
+			},
+		},
+		{
+			Project: "library",
+			Repo:    "web",
+			Tags: []Tag{
+				{Name: "v1", Labels: map[string]string{"team": "web"}},
+			},
+		},
+	}
+
+	cases := []struct {
+		selector string
+		expected []string
+	}{
+		{"team=data", []string{"library/app:v1", "library/app:v2"}},
+		{"retention!=permanent", []string{"library/app:v1", "library/app:v3", "library/app:v4", "library/web:v1"}},
+		{"team in (data,web),!retention", []string{"library/app:v1", "library/app:v3", "library/web:v1"}},
+		{"temporary", []string{"library/app:v3"}},
+		{"team notin (web)", []string{"library/app:v1", "library/app:v2", "library/app:v4"}},
+	}
+
+	for _, c := range cases {
+		selector, err := ParseLabelSelector(c.selector)
+		assert.Nil(t, err)
+		e := &labelScopedEvaluator{selector: selector, evaluator: allEvaluator{}}
+		selected, err := e.Evaluate(repos)
+		assert.Nil(t, err)
+
+		var names []string
+		for _, r := range repos {
+			for _, tag := range r.Tags {
+				if selected.Has(r, tag.Name) {
+					names = append(names, r.Project+"/"+r.Repo+":"+tag.Name)
+				}
+			}
+		}
+		assert.Equal(t, c.expected, names, c.selector)
+	}
+
+	_, err := ParseLabelSelector("team in data")
+	assert.NotNil(t, err)
+}
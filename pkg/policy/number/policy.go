@@ -2,29 +2,54 @@ package number
 
 import (
 	"fmt"
+	"regexp"
+	"time"
 
 	"github.com/cd1989/harbor-cleaner/pkg/config"
 	"github.com/cd1989/harbor-cleaner/pkg/harbor"
 	"github.com/cd1989/harbor-cleaner/pkg/policy"
 )
 
+// What to count for the number of images to retain
+const (
+	CountByTag    = "tag"
+	CountByDigest = "digest"
+)
+
 func init() {
 	policy.RegisterProcessorFactory(policy.NumberLimitPolicy, newFactory())
 }
 
 func newFactory() func(cfg config.C) policy.Processor {
 	return func(cfg config.C) policy.Processor {
-		return &numberPolicyProcessor{
+		p := &numberPolicyProcessor{
 			BaseProcessor: policy.BaseProcessor{
 				Client: harbor.APIClient,
 				Cfg:    cfg,
 			},
+			now: time.Now,
+		}
+		p.logs = func(startTime, endTime int64) ([]*harbor.AccessLog, error) {
+			return p.ListAccessLogs(startTime, endTime, harbor.AccessOperationPush)
 		}
+		return p
 	}
 }
 
+// groupCapture is name of the capture in 'groupBy' regex that defines the family of a tag
+const groupCapture = "group"
+
 type numberPolicyProcessor struct {
 	policy.BaseProcessor
+	groupBy *regexp.Regexp
+	now     func() time.Time
+	logs    func(startTime, endTime int64) ([]*harbor.AccessLog, error)
+}
+
+// family is a group of tags sharing the same family name
+type family struct {
+	name string
+	tags []policy.Tag
 }
 
 // Ensure (*numberPolicyProcessor) implements interface Processor
@@ -35,55 +60,230 @@ func (p *numberPolicyProcessor) GetPolicyType() policy.Type {
 	return policy.NumberLimitPolicy
 }
 
+// Validate validates the policy configuration.
+func (p *numberPolicyProcessor) Validate() error {
+	if p.Cfg.Policy.NumPolicy == nil {
+		return fmt.Errorf("policy.NumPolicy not configured")
+	}
+
+	if p.Cfg.Policy.NumPolicy.Num < 0 {
+		return fmt.Errorf("policy.numberPolicy.number should not be negative")
+	}
+
+	switch p.Cfg.Policy.NumPolicy.CountBy {
+	case "", CountByTag, CountByDigest:
+	default:
+		return fmt.Errorf("policy.numberPolicy.countBy should be '%s' or '%s'", CountByTag, CountByDigest)
+	}
+
+	switch p.Cfg.Policy.NumPolicy.SortBy {
+	case "", SortByCreated, SortByPushed, SortByName, SortBySemver:
+	default:
+		return fmt.Errorf("policy.numberPolicy.sortBy should be one of '%s', '%s', '%s', '%s'", SortByCreated, SortByPushed, SortByName, SortBySemver)
+	}
+
+	if p.Cfg.Policy.NumPolicy.PushWindow < 0 {
+		return fmt.Errorf("policy.numberPolicy.pushWindow should not be negative")
+	}
+
+	for name, n := range p.Cfg.Policy.NumPolicy.GroupNumbers {
+		if n < 0 {
+			return fmt.Errorf("policy.numberPolicy.groupNumbers[%s] should not be negative", name)
+		}
+	}
+
+	p.groupBy = nil
+	if len(p.Cfg.Policy.NumPolicy.GroupBy) > 0 {
+		r, err := regexp.Compile(p.Cfg.Policy.NumPolicy.GroupBy)
+		if err != nil {
+			return fmt.Errorf("compile regex %s error: %v", p.Cfg.Policy.NumPolicy.GroupBy, err)
+		}
+		if captureIndex(r) < 0 {
+			return fmt.Errorf("policy.numberPolicy.groupBy should have a named capture '%s', e.g. '^(?P<%s>.+)-[0-9a-f]+$'", groupCapture, groupCapture)
+		}
+		p.groupBy = r
+	} else if len(p.Cfg.Policy.NumPolicy.GroupNumbers) > 0 {
+		return fmt.Errorf("policy.numberPolicy.groupNumbers takes effect only when policy.numberPolicy.groupBy configured")
+	}
+
+	return nil
+}
+
 // ListCandidates list all candidates to be remove based on the policy
 func (p *numberPolicyProcessor) ListCandidates() ([]*policy.Candidate, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
 	images, err := p.ListTags()
 	if err != nil {
 		return nil, err
 	}
 
-	if p.Cfg.Policy.NumPolicy == nil {
-		return nil, fmt.Errorf("policy.NumPolicy not configured")
+	selected, err := p.Evaluate(images)
+	if err != nil {
+		return nil, err
 	}
 
-	var imagesToClean []*policy.Candidate
-	for _, r := range images {
-		if len(r.Tags) <= p.Cfg.Policy.NumPolicy.Num {
-			continue
+	return policy.BuildCandidates(images, selected, p.Cfg.Policy.RetainTags), nil
+}
+
+// Evaluate selects tags to remove from the given repos
+func (p *numberPolicyProcessor) Evaluate(images []*policy.RepoTags) (policy.Selection, error) {
+	var pushed map[string]map[string]time.Time
+	if p.Cfg.Policy.NumPolicy.SortBy == SortByPushed {
+		var err error
+		if pushed, err = p.pushTimes(images); err != nil {
+			return nil, err
 		}
+	}
 
-		var candidates []policy.Tag
-		var remains = r.Tags[0:p.Cfg.Policy.NumPolicy.Num]
-		for _, t := range r.Tags[p.Cfg.Policy.NumPolicy.Num:] {
-			if !policy.Retain(p.Cfg.Policy.RetainTags, t.Name) {
-				candidates = append(candidates, t)
-				continue
+	selected := make(policy.Selection)
+	for _, r := range images {
+		tags := sortTags(r.Tags, p.Cfg.Policy.NumPolicy.SortBy, pushed[fmt.Sprintf("%s/%s", r.Project, r.Repo)])
+		for _, f := range p.families(tags) {
+			for _, t := range p.beyondNewest(f.tags, p.numberOf(f.name)) {
+				selected.Add(r, t.Name)
 			}
+		}
+	}
+
+	return selected, nil
+}
+
+// families groups tags into families by the 'groupBy' regex, order of tags is kept in each family.
+// If 'groupBy' not configured, all tags are in one family.
+func (p *numberPolicyProcessor) families(tags []policy.Tag) []*family {
+	if p.groupBy == nil {
+		return []*family{{tags: tags}}
+	}
 
-			remains = append(remains, t)
+	var families []*family
+	byName := make(map[string]*family)
+	index := captureIndex(p.groupBy)
+	for _, t := range tags {
+		name := ""
+		if m := p.groupBy.FindStringSubmatch(t.Name); m != nil {
+			name = m[index]
 		}
 
-		remainsDigests := make(map[string][]string)
-		for _, t := range remains {
-			remainsDigests[t.Digest] = append(remainsDigests[t.Digest], t.Name)
+		f, ok := byName[name]
+		if !ok {
+			f = &family{name: name}
+			byName[name] = f
+			families = append(families, f)
+		}
+		f.tags = append(f.tags, t)
+	}
+
+	return families
+}
+
+// captureIndex gets index of the 'group' capture in the regex, -1 if not found.
+func captureIndex(r *regexp.Regexp) int {
+	for i, name := range r.SubexpNames() {
+		if name == groupCapture {
+			return i
+		}
+	}
+	return -1
+}
+
+// numberOf gets number of images to retain for the family.
+func (p *numberPolicyProcessor) numberOf(family string) int {
+	if n, ok := p.Cfg.Policy.NumPolicy.GroupNumbers[family]; ok {
+		return n
+	}
+	return p.Cfg.Policy.NumPolicy.Num
+}
+
+// beyondNewest gets tags beyond the newest n, tags should be sorted from the newest to the oldest.
+func (p *numberPolicyProcessor) beyondNewest(tags []policy.Tag, n int) []policy.Tag {
+	if p.Cfg.Policy.NumPolicy.CountBy == CountByDigest {
+		return beyondNewestDigests(tags, n)
+	}
+
+	if len(tags) <= n {
+		return nil
+	}
+	return tags[n:]
+}
+
+// beyondNewestDigests gets tags that don't point to any of the newest n digests, tags should be
+// sorted from the newest to the oldest.
+func beyondNewestDigests(tags []policy.Tag, n int) []policy.Tag {
+	newest := make(map[string]bool)
+	var beyond []policy.Tag
+	for _, t := range tags {
+		if newest[t.Digest] {
+			continue
 		}
+		if len(newest) < n {
+			newest[t.Digest] = true
+			continue
+		}
+		beyond = append(beyond, t)
+	}
 
-		dangerTags := make(map[string][]string)
-		for _, t := range candidates {
-			if tags, ok := remainsDigests[t.Digest]; ok {
-				dangerTags[t.Digest] = tags
+	return beyond
+}
+
+const (
+	// DefaultPushWindow is the default look-back window of push logs
+	DefaultPushWindow = config.Duration(time.Hour * 24 * 90)
+	// clockSkew is the margin of clock skew between image builders and Harbor.
+	clockSkew = time.Hour * 24
+)
+
+// harborEpoch is before the first release of Harbor, creation time earlier than it is bogus, e.g.
+// images built reproducibly with creation time set to epoch.
+var harborEpoch = time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// pushTimes gets the latest push time of tags from access logs, it maps 'project/repo' to a map
+// from tag name to push time. Logs are listed within the 'pushWindow'. An image can't be pushed
+// before it's created, so if all images are created later, logs are listed since creation of the
+// oldest image, with a margin of clock skew between builders and Harbor. Zero and bogus creation
+// time are ignored.
+func (p *numberPolicyProcessor) pushTimes(images []*policy.RepoTags) (map[string]map[string]time.Time, error) {
+	window := p.Cfg.Policy.NumPolicy.PushWindow
+	if window == 0 {
+		window = DefaultPushWindow
+	}
+
+	now := p.now()
+	oldest := now
+	for _, r := range images {
+		for _, t := range r.Tags {
+			if t.Created.Before(harborEpoch) || t.Created.After(now) {
+				continue
+			}
+			if t.Created.Before(oldest) {
+				oldest = t.Created
 			}
 		}
+	}
+	start := oldest.Add(-clockSkew)
+	if windowStart := now.Add(-window.Duration()); start.Before(windowStart) {
+		start = windowStart
+	}
+
+	logs, err := p.logs(start.Unix(), now.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("list push logs error: %v", err)
+	}
 
-		if len(candidates) > 0 {
-			imagesToClean = append(imagesToClean, &policy.Candidate{
-				Project:   r.Project,
-				Repo:      r.Repo,
-				Tags:      candidates,
-				Protected: dangerTags,
-			})
+	pushed := make(map[string]map[string]time.Time)
+	for _, log := range logs {
+		if log.Operation != harbor.AccessOperationPush {
+			continue
+		}
+		if _, ok := pushed[log.RepoName]; !ok {
+			pushed[log.RepoName] = make(map[string]time.Time)
+		}
+		if log.OpTime.After(pushed[log.RepoName][log.Tag]) {
+			pushed[log.RepoName][log.Tag] = log.OpTime
 		}
 	}
 
-	return imagesToClean, nil
+	return pushed, nil
 }
@@ -0,0 +1,108 @@
+package number
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+func TestBeyondNewestDigests(t *testing.T) {
+	tags := []policy.Tag{
+		{Name: "latest", Digest: "d3"},
+		{Name: "1.2.3", Digest: "d3"},
+		{Name: "1.2", Digest: "d3"},
+		{Name: "1.2.2", Digest: "d2"},
+		{Name: "1.2.1", Digest: "d1"},
+		{Name: "1.1", Digest: "d2"},
+		{Name: "1.1.9", Digest: "d0"},
+	}
+
+	assert.Equal(t, []string{"1.2.1", "1.1.9"}, names(beyondNewestDigests(tags, 2)))
+	assert.Equal(t, []string{"1.2.2", "1.2.1", "1.1", "1.1.9"}, names(beyondNewestDigests(tags, 1)))
+	assert.Nil(t, beyondNewestDigests(tags, 4))
+}
+
+func TestGroupedRetention(t *testing.T) {
+	now := time.Now()
+	repo := &policy.RepoTags{
+		Project: "library",
+		Repo:    "app",
+		Tags: []policy.Tag{
+			{Name: "feature-login-9f8e7d", Digest: "d1", Created: now.Add(-time.Hour * 1)},
+			{Name: "main-ab12cd", Digest: "d2", Created: now.Add(-time.Hour * 2)},
+			{Name: "feature-login-8e7d6c", Digest: "d3", Created: now.Add(-time.Hour * 3)},
+			{Name: "feature-login-7d6c5b", Digest: "d4", Created: now.Add(-time.Hour * 4)},
+			{Name: "main-bc23de", Digest: "d5", Created: now.Add(-time.Hour * 5)},
+			{Name: "latest", Digest: "d2", Created: now.Add(-time.Hour * 6)},
+			{Name: "main-cd34ef", Digest: "d6", Created: now.Add(-time.Hour * 7)},
+		},
+	}
+
+	p := newFactory()(config.C{Policy: config.Policy{NumPolicy: &config.NumPolicy{
+		Num:          1,
+		GroupBy:      "^(?P<group>.+)-[0-9a-f]{6}$",
+		GroupNumbers: map[string]int{"main": 2},
+	}}}).(*numberPolicyProcessor)
+	assert.Nil(t, p.Validate())
+
+	selected, err := p.Evaluate([]*policy.RepoTags{repo})
+	assert.Nil(t, err)
+	var names []string
+	for _, tag := range repo.Tags {
+		if selected.Has(repo, tag.Name) {
+			names = append(names, tag.Name)
+		}
+	}
+	assert.Equal(t, []string{"feature-login-8e7d6c", "feature-login-7d6c5b", "main-cd34ef"}, names)
+
+	p.Cfg.Policy.NumPolicy.GroupBy = "^(.+)-[0-9a-f]{6}$"
+	assert.NotNil(t, p.Validate())
+}
+
+func TestPushTimes(t *testing.T) {
+	now := time.Now()
+	day := time.Hour * 24
+	logs := []*harbor.AccessLog{
+		{RepoName: "library/app", Tag: "1.0", Operation: harbor.AccessOperationPush, OpTime: now.Add(-day)},
+		{RepoName: "library/app", Tag: "1.0", Operation: harbor.AccessOperationPush, OpTime: now.Add(-day * 3)},
+		{RepoName: "library/app", Tag: "1.0", Operation: harbor.AccessOperationPull, OpTime: now},
+	}
+
+	cases := []struct {
+		window  config.Duration
+		created []time.Time
+		start   time.Time
+	}{
+		// Bounded by the default window, epoch and zero creation time ignored
+		{0, []time.Time{{}, time.Unix(0, 0), now.Add(-day * 1000)}, now.Add(-DefaultPushWindow.Duration())},
+		{config.Duration(day * 30), []time.Time{now.Add(-day * 1000)}, now.Add(-day * 30)},
+		// Since creation of the oldest image
+		{0, []time.Time{now.Add(-day * 10), now.Add(-day * 5)}, now.Add(-day * 11)},
+		{0, nil, now.Add(-clockSkew)},
+	}
+
+	for _, c := range cases {
+		repo := &policy.RepoTags{Project: "library", Repo: "app"}
+		for _, created := range c.created {
+			repo.Tags = append(repo.Tags, policy.Tag{Name: "1.0", Created: created})
+		}
+
+		p := newFactory()(config.C{Policy: config.Policy{NumPolicy: &config.NumPolicy{SortBy: SortByPushed, PushWindow: c.window}}}).(*numberPolicyProcessor)
+		p.now = func() time.Time { return now }
+		var start int64
+		p.logs = func(startTime, endTime int64) ([]*harbor.AccessLog, error) {
+			start = startTime
+			return logs, nil
+		}
+
+		pushed, err := p.pushTimes([]*policy.RepoTags{repo})
+		assert.Nil(t, err)
+		assert.Equal(t, c.start.Unix(), start)
+		assert.Equal(t, now.Add(-day), pushed["library/app"]["1.0"])
+	}
+}
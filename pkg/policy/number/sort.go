@@ -0,0 +1,188 @@
+package number
+
+import (
+	"sort"
+	"time"
+
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+	"github.com/cd1989/harbor-cleaner/pkg/semver"
+)
+
+// Sort keys to order tags in a repo, tags are ordered from the newest to the oldest.
+const (
+	// SortByCreated orders tags by creation time in image config, it's the build time.
+	SortByCreated = "created"
+	// SortByPushed orders tags by the latest push time found in access logs, tags without push logs
+	// fallback to creation time.
+	SortByPushed = "pushed"
+	// SortByName orders tags by name in natural order, numbers in names are compared numerically,
+	// for example, 'build-1042' is newer than 'build-998'.
+	SortByName = "name"
+	// SortBySemver orders tags by semantic version, tags that are not semantic versions are regarded
+	// older than all semantic versions.
+	SortBySemver = "semver"
+)
+
+// tagSorter sorts tags from the newest to the oldest by the sort key. Ties are broken by creation
+// time and then by name, so the order is deterministic.
+type tagSorter struct {
+	tags     []policy.Tag
+	compare  func(a, b *policy.Tag) int
+	versions map[string]*semver.Version
+	pushed   map[string]time.Time
+}
+
+// sortTags returns a sorted copy of the tags, 'pushed' maps tag name to its push time, it's only
+// needed when sort by push time.
+func sortTags(tags []policy.Tag, sortBy string, pushed map[string]time.Time) []policy.Tag {
+	s := &tagSorter{
+		tags:   append([]policy.Tag{}, tags...),
+		pushed: pushed,
+	}
+
+	switch sortBy {
+	case SortByPushed:
+		s.compare = s.comparePushed
+	case SortByName:
+		s.compare = func(a, b *policy.Tag) int {
+			return CompareNatural(a.Name, b.Name)
+		}
+	case SortBySemver:
+		s.versions = make(map[string]*semver.Version)
+		for _, t := range tags {
+			if v, err := semver.Parse(t.Name); err == nil {
+				s.versions[t.Name] = v
+			}
+		}
+		s.compare = s.compareSemver
+	default:
+		s.compare = compareCreated
+	}
+
+	sort.Sort(s)
+	return s.tags
+}
+
+func (s *tagSorter) Len() int {
+	return len(s.tags)
+}
+
+func (s *tagSorter) Swap(i, j int) {
+	s.tags[i], s.tags[j] = s.tags[j], s.tags[i]
+}
+
+// Less reports whether tag i is newer than tag j
+func (s *tagSorter) Less(i, j int) bool {
+	a, b := &s.tags[i], &s.tags[j]
+	if c := s.compare(a, b); c != 0 {
+		return c > 0
+	}
+	if c := compareCreated(a, b); c != 0 {
+		return c > 0
+	}
+	return CompareNatural(a.Name, b.Name) > 0
+}
+
+func (s *tagSorter) comparePushed(a, b *policy.Tag) int {
+	return compareTime(s.pushTime(a), s.pushTime(b))
+}
+
+func (s *tagSorter) pushTime(t *policy.Tag) time.Time {
+	if pushed, ok := s.pushed[t.Name]; ok {
+		return pushed
+	}
+	return t.Created
+}
+
+func (s *tagSorter) compareSemver(a, b *policy.Tag) int {
+	va, vb := s.versions[a.Name], s.versions[b.Name]
+	switch {
+	case va != nil && vb != nil:
+		return va.Compare(vb)
+	case va != nil:
+		return 1
+	case vb != nil:
+		return -1
+	default:
+		return 0
+	}
+}
+
+func compareCreated(a, b *policy.Tag) int {
+	return compareTime(a.Created, b.Created)
+}
+
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.After(b):
+		return 1
+	case a.Before(b):
+		return -1
+	default:
+		return 0
+	}
+}
+
+// CompareNatural compares two strings in natural order, digit sequences are compared numerically.
+// It returns -1, 0, 1 if a is less than, equal to or greater than b.
+func CompareNatural(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			si := i
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			sj := j
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+
+			// Compare numerically by length after trimming leading zeros, then lexically
+			na, nb := trimZeros(a[si:i]), trimZeros(b[sj:j])
+			if len(na) != len(nb) {
+				return compareInt(len(na), len(nb))
+			}
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+			continue
+		}
+
+		if a[i] != b[j] {
+			if a[i] < b[j] {
+				return -1
+			}
+			return 1
+		}
+		i++
+		j++
+	}
+
+	return compareInt(len(a)-i, len(b)-j)
+}
+
+func trimZeros(s string) string {
+	for len(s) > 1 && s[0] == '0' {
+		s = s[1:]
+	}
+	return s
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,52 @@
+package number
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+func names(tags []policy.Tag) []string {
+	var result []string
+	for _, t := range tags {
+		result = append(result, t.Name)
+	}
+	return result
+}
+
+func TestCompareNatural(t *testing.T) {
+	assert.Equal(t, 1, CompareNatural("build-1042", "build-998"))
+	assert.Equal(t, -1, CompareNatural("build-9", "build-10"))
+	assert.Equal(t, 0, CompareNatural("build-010", "build-10"))
+	assert.Equal(t, -1, CompareNatural("v1.2", "v1.10"))
+	assert.Equal(t, 1, CompareNatural("b", "a"))
+	assert.Equal(t, -1, CompareNatural("build", "build-1"))
+}
+
+func TestSortTags(t *testing.T) {
+	epoch := time.Unix(0, 0)
+	now := time.Now()
+	tags := []policy.Tag{
+		{Name: "build-998", Created: epoch},
+		{Name: "v1.10.0", Created: epoch},
+		{Name: "build-1042", Created: epoch},
+		{Name: "v1.9.0", Created: now},
+		{Name: "latest", Created: now},
+	}
+
+	assert.Equal(t, []string{"v1.9.0", "latest", "v1.10.0", "build-1042", "build-998"}, names(sortTags(tags, SortByCreated, nil)))
+	assert.Equal(t, []string{"v1.10.0", "v1.9.0", "latest", "build-1042", "build-998"}, names(sortTags(tags, SortByName, nil)))
+	assert.Equal(t, []string{"v1.10.0", "v1.9.0", "latest", "build-1042", "build-998"}, names(sortTags(tags, SortBySemver, nil)))
+
+	pushed := map[string]time.Time{
+		"build-998":  now.Add(time.Hour),
+		"build-1042": now.Add(time.Hour * 2),
+	}
+	assert.Equal(t, []string{"build-1042", "build-998", "v1.9.0", "latest", "v1.10.0"}, names(sortTags(tags, SortByPushed, pushed)))
+
+	// Original tags should not be reordered
+	assert.Equal(t, "build-998", tags[0].Name)
+}
@@ -16,6 +16,16 @@ const (
 	NumberLimitPolicy        Type = "number"
 	RecentlyNotTouchedPolicy Type = "recentlyNotTouched"
 	RegexPolicy              Type = "regex"
+	AgePolicy                Type = "age"
+	CompositePolicy          Type = "composite"
+	SemverPolicy             Type = "semver"
+	BucketsPolicy            Type = "buckets"
+	SizePolicy               Type = "size"
+	QuotaPolicy              Type = "quota"
+	PopularityPolicy         Type = "popularity"
+	GitPolicy                Type = "git"
+	PullRequestPolicy        Type = "pullRequest"
+	ExpiryPolicy             Type = "expiry"
 )
 
 // Processor defines process interface of a clean policy.
@@ -28,6 +38,13 @@ type Processor interface {
 	GetPolicyType() Type
 }
 
+// Validator is implemented by processors that can validate their configuration without accessing
+// Harbor, it's used to check a config before any run.
+type Validator interface {
+	// Validate checks configuration of the policy.
+	Validate() error
+}
+
 // processorFactoryRegistry stores factories for all supported policy processors
 var processorFactoryRegistry = make(map[Type]func(cfg config.C) Processor)
 
@@ -46,6 +63,26 @@ func GetProcessorFactory(policyType Type) func(cfg config.C) Processor {
 	return factory
 }
 
+// Validate validates the policy configured in the config.
+func Validate(cfg config.C) error {
+	if _, err := NewScope(cfg.Projects, cfg.Repos); err != nil {
+		return err
+	}
+
+	factory := GetProcessorFactory(Type(cfg.Policy.Type))
+	if factory == nil {
+		return fmt.Errorf("no processor factory found for policy type: %s", cfg.Policy.Type)
+	}
+
+	if v, ok := factory(cfg).(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return validateScopedPolicies(cfg)
+}
+
 // BaseProcessor defines base logic for policy processor
 type BaseProcessor struct {
 	Cfg    config.C
@@ -65,54 +102,60 @@ func (p *BaseProcessor) ListCandidates() ([]*Candidate, error) {
 	return nil, fmt.Errorf("ListCandidates not implemented")
 }
 
-// ListTags lists all tags
+// ListTags lists all tags of repos selected by the configured scope
 func (p *BaseProcessor) ListTags() ([]*RepoTags, error) {
-	projects, err := p.Client.AllProjects("", "")
+	scope, err := NewScope(p.Cfg.Projects, p.Cfg.Repos)
 	if err != nil {
-		logrus.Errorf("List projects error: %v", err)
 		return nil, err
 	}
 
-	if len(p.Cfg.Projects) != 0 {
-		projectsMap := make(map[string]*harbor.Project)
-		for _, p := range projects {
-			projectsMap[p.Name] = p
-		}
-
-		var configuredProjects []*harbor.Project
-		for _, p := range p.Cfg.Projects {
-			if pinfo, ok := projectsMap[p]; ok {
-				configuredProjects = append(configuredProjects, pinfo)
-			} else {
-				return nil, fmt.Errorf("project %s not found", p)
-			}
-		}
-		projects = configuredProjects
+	projects, err := p.listProjects(scope)
+	if err != nil {
+		return nil, err
 	}
 
 	var results []*RepoTags
 	for _, pinfo := range projects {
 		logrus.Infof("Start to collect images for project '%s'", pinfo.Name)
-		repos, err := p.Client.ListAllRepositories(pinfo.ProjectID)
-		if err != nil {
-			return nil, fmt.Errorf("list repos for project '%s' error: %v", pinfo.Name, err)
+		repos, ok := scope.LiteralRepos(pinfo.Name)
+		if !ok {
+			all, err := p.Client.ListAllRepositories(pinfo.ProjectID)
+			if err != nil {
+				return nil, fmt.Errorf("list repos for project '%s' error: %v", pinfo.Name, err)
+			}
+
+			repos = nil
+			for _, repo := range all {
+				_, r := utils.ParseRepository(repo.Name)
+				if scope.MatchRepo(pinfo.Name, r) {
+					repos = append(repos, r)
+				}
+			}
 		}
 
-		for _, repo := range repos {
-			proj, r := utils.ParseRepository(repo.Name)
-			tags, err := p.Client.ListTags(proj, r)
+		for _, r := range repos {
+			tags, err := p.Client.ListTags(pinfo.Name, r)
 			if err != nil {
-				logrus.Errorf("List tags for '%s/%s' error: %v", proj, r, err)
+				logrus.Errorf("List tags for '%s/%s' error: %v", pinfo.Name, r, err)
 				continue
 			}
 
 			var tagsInfo []Tag
 			for _, tag := range tags {
-				tagsInfo = append(tagsInfo, Tag{
-					Name:    tag.Name,
-					Digest:  tag.Digest,
-					Created: tag.Created,
-				})
+				t := Tag{
+					Name:          tag.Name,
+					Digest:        tag.Digest,
+					Created:       tag.Created,
+					Size:          tag.Size,
+					Architecture:  tag.Architecture,
+					OS:            tag.OS,
+					Author:        tag.Author,
+					DockerVersion: tag.DockerVersion,
+				}
+				if tag.Config != nil {
+					t.Labels = tag.Config.Labels
+				}
+				tagsInfo = append(tagsInfo, t)
 			}
 
 			results = append(results, &RepoTags{Project: pinfo.Name, Repo: r, Tags: tagsInfo})
@@ -121,3 +164,61 @@ func (p *BaseProcessor) ListTags() ([]*RepoTags, error) {
 
 	return results, nil
 }
+
+// listProjects lists projects that may have repos selected by the scope. If all project patterns
+// are literal names, projects are queried by name, otherwise all projects are listed and matched.
+func (p *BaseProcessor) listProjects(scope *Scope) ([]*harbor.Project, error) {
+	patterns := scope.ProjectPatterns()
+	literal := !scope.All()
+	for _, pattern := range patterns {
+		if IsGlob(pattern) {
+			literal = false
+		}
+	}
+
+	if !literal {
+		projects, err := p.Client.AllProjects("", "")
+		if err != nil {
+			logrus.Errorf("List projects error: %v", err)
+			return nil, err
+		}
+
+		var matched []*harbor.Project
+		for _, pinfo := range projects {
+			if scope.MatchProject(pinfo.Name) {
+				matched = append(matched, pinfo)
+			}
+		}
+		return matched, nil
+	}
+
+	var projects []*harbor.Project
+	seen := make(map[string]bool)
+	for _, name := range patterns {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		// Projects are queried by name fuzzily, so need to pick the exact one.
+		candidates, err := p.Client.AllProjects(name, "")
+		if err != nil {
+			logrus.Errorf("List projects with name '%s' error: %v", name, err)
+			return nil, err
+		}
+
+		var found *harbor.Project
+		for _, pinfo := range candidates {
+			if pinfo.Name == name {
+				found = pinfo
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("project %s not found", name)
+		}
+		projects = append(projects, found)
+	}
+
+	return projects, nil
+}
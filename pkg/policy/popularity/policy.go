@@ -0,0 +1,173 @@
+package popularity
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+func init() {
+	policy.RegisterProcessorFactory(policy.PopularityPolicy, newFactory())
+}
+
+func newFactory() func(cfg config.C) policy.Processor {
+	return func(cfg config.C) policy.Processor {
+		p := &popularityPolicyProcessor{
+			BaseProcessor: policy.BaseProcessor{
+				Client: harbor.APIClient,
+				Cfg:    cfg,
+			},
+			now: time.Now,
+		}
+		p.logs = func(startTime, endTime int64) ([]*harbor.AccessLog, error) {
+			return p.ListAccessLogs(startTime, endTime, "")
+		}
+		return p
+	}
+}
+
+// popularityPolicyProcessor cleans images by usage counted from access logs. Tags used fewer than
+// 'minPulls' times are removed, and only the top N most used tags in each repo are retained if 'top'
+// configured. Ties in ranking are broken by creation time, newer first. Tags younger than 'minAge'
+// are kept, as they may not have been pulled yet.
+type popularityPolicyProcessor struct {
+	policy.BaseProcessor
+	now  func() time.Time
+	logs func(startTime, endTime int64) ([]*harbor.AccessLog, error)
+}
+
+// Ensure (*popularityPolicyProcessor) implements interface Processor
+var _ policy.Processor = (*popularityPolicyProcessor)(nil)
+
+// GetPolicyType gets policy type.
+func (p *popularityPolicyProcessor) GetPolicyType() policy.Type {
+	return policy.PopularityPolicy
+}
+
+// Validate validates the policy configuration.
+func (p *popularityPolicyProcessor) Validate() error {
+	cfg := p.Cfg.Policy.PopularityPolicy
+	if cfg == nil {
+		return fmt.Errorf("policy.popularityPolicy not configured, it's necessary when policy.type == 'popularity'")
+	}
+
+	if cfg.Window <= 0 {
+		return fmt.Errorf("policy.popularityPolicy.window should be positive")
+	}
+
+	if cfg.MinAge < 0 {
+		return fmt.Errorf("policy.popularityPolicy.minAge should not be negative")
+	}
+
+	if cfg.MinPulls < 0 || cfg.Top < 0 {
+		return fmt.Errorf("policy.popularityPolicy.minPulls and policy.popularityPolicy.top should not be negative")
+	}
+
+	if cfg.MinPulls == 0 && cfg.Top == 0 {
+		return fmt.Errorf("policy.popularityPolicy should have at least one of 'minPulls' and 'top' configured")
+	}
+
+	for _, op := range cfg.Operations {
+		switch op {
+		case harbor.AccessOperationPull, harbor.AccessOperationPush, harbor.AccessOperationDelete:
+		default:
+			return fmt.Errorf("unsupported operation '%s' in policy.popularityPolicy.operations, should be one of '%s', '%s', '%s'",
+				op, harbor.AccessOperationPull, harbor.AccessOperationPush, harbor.AccessOperationDelete)
+		}
+	}
+
+	return nil
+}
+
+// ListCandidates list all candidates to be remove based on the policy
+func (p *popularityPolicyProcessor) ListCandidates() ([]*policy.Candidate, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	images, err := p.ListTags()
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := p.Evaluate(images)
+	if err != nil {
+		return nil, err
+	}
+
+	return policy.BuildCandidates(images, selected, p.Cfg.Policy.RetainTags), nil
+}
+
+// Evaluate selects tags to remove from the given repos
+func (p *popularityPolicyProcessor) Evaluate(images []*policy.RepoTags) (policy.Selection, error) {
+	cfg := p.Cfg.Policy.PopularityPolicy
+	counts, err := p.usageCounts()
+	if err != nil {
+		return nil, err
+	}
+
+	minAge := cfg.MinAge
+	if minAge == 0 {
+		minAge = cfg.Window
+	}
+	deadline := p.now().Add(-minAge.Duration())
+
+	selected := make(policy.Selection)
+	for _, r := range images {
+		tags := make([]policy.Tag, len(r.Tags))
+		copy(tags, r.Tags)
+		countOf := func(t policy.Tag) int {
+			return counts[fmt.Sprintf("%s/%s:%s", r.Project, r.Repo, t.Name)]
+		}
+		sort.SliceStable(tags, func(i, j int) bool {
+			ci, cj := countOf(tags[i]), countOf(tags[j])
+			if ci != cj {
+				return ci > cj
+			}
+			return tags[i].Created.After(tags[j].Created)
+		})
+
+		for i, t := range tags {
+			if t.Created.After(deadline) {
+				continue
+			}
+			if countOf(t) < cfg.MinPulls || (cfg.Top > 0 && i >= cfg.Top) {
+				selected.Add(r, t.Name)
+			}
+		}
+	}
+
+	return selected, nil
+}
+
+// usageCounts counts access logs of the configured operations within the window, it's keyed by
+// 'project/repo:tag'.
+func (p *popularityPolicyProcessor) usageCounts() (map[string]int, error) {
+	cfg := p.Cfg.Policy.PopularityPolicy
+	operations := make(map[string]bool)
+	for _, op := range cfg.Operations {
+		operations[op] = true
+	}
+	if len(operations) == 0 {
+		operations[harbor.AccessOperationPull] = true
+	}
+
+	endTime := p.now().Unix()
+	logs, err := p.logs(endTime-cfg.Window.Seconds(), endTime)
+	if err != nil {
+		return nil, fmt.Errorf("list access logs error: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for _, log := range logs {
+		if operations[log.Operation] {
+			counts[fmt.Sprintf("%s:%s", log.RepoName, log.Tag)]++
+		}
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,94 @@
+package popularity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Now()
+	day := time.Hour * 24
+	repo := &policy.RepoTags{
+		Project: "library",
+		Repo:    "app",
+		Tags: []policy.Tag{
+			{Name: "v4", Created: now.Add(-day * 4)},
+			{Name: "v3", Created: now.Add(-day * 5)},
+			{Name: "v2", Created: now.Add(-day * 6)},
+			{Name: "v1", Created: now.Add(-day * 7)},
+		},
+	}
+	var logs []*harbor.AccessLog
+	for tag, pulls := range map[string]int{"v3": 3, "v1": 2} {
+		for i := 0; i < pulls; i++ {
+			logs = append(logs, &harbor.AccessLog{RepoName: "library/app", Tag: tag, Operation: harbor.AccessOperationPull})
+		}
+	}
+	logs = append(logs,
+		&harbor.AccessLog{RepoName: "library/app", Tag: "v4", Operation: harbor.AccessOperationPush},
+		&harbor.AccessLog{RepoName: "library/app", Tag: "v2", Operation: harbor.AccessOperationPush},
+		&harbor.AccessLog{RepoName: "library/app", Tag: "v2", Operation: harbor.AccessOperationPush},
+	)
+
+	cases := []struct {
+		policy   config.PopularityPolicy
+		expected []string
+	}{
+		{
+			config.PopularityPolicy{MinPulls: 2},
+			[]string{"v4", "v2"},
+		},
+		{
+			config.PopularityPolicy{Top: 2},
+			[]string{"v4", "v2"},
+		},
+		{
+			config.PopularityPolicy{Top: 3, Operations: []string{harbor.AccessOperationPull, harbor.AccessOperationPush}},
+			[]string{"v4"},
+		},
+		{
+			config.PopularityPolicy{MinPulls: 2, MinAge: config.Duration(day*4 + time.Hour)},
+			[]string{"v2"},
+		},
+	}
+
+	for _, c := range cases {
+		c.policy.Window = config.Duration(day)
+		p := newFactory()(config.C{Policy: config.Policy{PopularityPolicy: &c.policy}}).(*popularityPolicyProcessor)
+		p.now = func() time.Time { return now }
+		p.logs = func(startTime, endTime int64) ([]*harbor.AccessLog, error) {
+			return logs, nil
+		}
+		assert.Nil(t, p.Validate())
+		selected, err := p.Evaluate([]*policy.RepoTags{repo})
+		assert.Nil(t, err)
+
+		var names []string
+		for _, tag := range repo.Tags {
+			if selected.Has(repo, tag.Name) {
+				names = append(names, tag.Name)
+			}
+		}
+		assert.Equal(t, c.expected, names)
+	}
+
+	// Tags younger than the window are kept by default even if not pulled.
+	p := newFactory()(config.C{Policy: config.Policy{PopularityPolicy: &config.PopularityPolicy{
+		Window:   config.Duration(day * 5),
+		MinPulls: 1,
+	}}}).(*popularityPolicyProcessor)
+	p.now = func() time.Time { return now }
+	p.logs = func(startTime, endTime int64) ([]*harbor.AccessLog, error) {
+		return logs, nil
+	}
+	selected, err := p.Evaluate([]*policy.RepoTags{repo})
+	assert.Nil(t, err)
+	assert.False(t, selected.Has(repo, "v4"))
+	assert.True(t, selected.Has(repo, "v2"))
+}
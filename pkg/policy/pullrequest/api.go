@@ -0,0 +1,113 @@
+package pullrequest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// ProviderGitHub is GitHub compatible API
+	ProviderGitHub = "github"
+	// ProviderGitLab is GitLab compatible API
+	ProviderGitLab = "gitlab"
+)
+
+// errNotFound indicates the pull request doesn't exist.
+var errNotFound = fmt.Errorf("pull request not found")
+
+// pullRequest is state of a pull request, 'ClosedAt' is zero if it's still open.
+type pullRequest struct {
+	Open     bool
+	ClosedAt time.Time
+}
+
+// api queries pull requests from GitHub or GitLab compatible API.
+type api struct {
+	provider string
+	baseURL  string
+	repo     string
+	token    string
+	client   *http.Client
+}
+
+func newAPI(provider, baseURL, repo, token string) *api {
+	if len(provider) == 0 {
+		provider = ProviderGitHub
+	}
+	return &api{
+		provider: provider,
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		repo:     repo,
+		token:    token,
+		client:   &http.Client{Timeout: time.Minute},
+	}
+}
+
+// get gets state of the pull request (merge request for GitLab) with the given number.
+func (a *api) get(number int) (*pullRequest, error) {
+	var path string
+	if a.provider == ProviderGitLab {
+		path = fmt.Sprintf("%s/projects/%s/merge_requests/%d", a.baseURL, url.PathEscape(a.repo), number)
+	} else {
+		path = fmt.Sprintf("%s/repos/%s/pulls/%d", a.baseURL, a.repo, number)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(a.token) > 0 {
+		if a.provider == ProviderGitLab {
+			req.Header.Set("PRIVATE-TOKEN", a.token)
+		} else {
+			req.Header.Set("Authorization", "token "+a.token)
+		}
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("get pull request %d error: %s, %s", number, resp.Status, body)
+	}
+
+	// GitHub states are 'open' and 'closed', GitLab states are 'opened', 'closed', 'merged' and 'locked'.
+	ret := &struct {
+		State    string     `json:"state"`
+		ClosedAt *time.Time `json:"closed_at"`
+		MergedAt *time.Time `json:"merged_at"`
+	}{}
+	if err := json.Unmarshal(body, ret); err != nil {
+		return nil, err
+	}
+
+	pr := &pullRequest{}
+	switch ret.State {
+	case "open", "opened", "locked":
+		pr.Open = true
+	default:
+		if ret.MergedAt != nil {
+			pr.ClosedAt = *ret.MergedAt
+		}
+		if ret.ClosedAt != nil && ret.ClosedAt.After(pr.ClosedAt) {
+			pr.ClosedAt = *ret.ClosedAt
+		}
+	}
+
+	return pr, nil
+}
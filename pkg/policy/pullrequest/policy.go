@@ -0,0 +1,171 @@
+package pullrequest
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+// DefaultTagPattern matches tags like 'pr-123' and 'pr-123-abcdef0'
+const DefaultTagPattern = `^pr-(?P<pr>[0-9]+)(-.*)?$`
+
+func init() {
+	policy.RegisterProcessorFactory(policy.PullRequestPolicy, newFactory())
+}
+
+func newFactory() func(cfg config.C) policy.Processor {
+	return func(cfg config.C) policy.Processor {
+		p := &pullRequestPolicyProcessor{
+			BaseProcessor: policy.BaseProcessor{
+				Client: harbor.APIClient,
+				Cfg:    cfg,
+			},
+			now: time.Now,
+		}
+		if c := cfg.Policy.PullRequestPolicy; c != nil {
+			p.pulls = newAPI(c.Provider, c.BaseURL, c.Repo, c.Token).get
+		}
+		return p
+	}
+}
+
+// pullRequestPolicyProcessor cleans images built for pull requests. Pull request number is parsed
+// from tag name, and images of pull requests closed or merged longer than the grace period ago are
+// removed. Images of open pull requests, of pull requests not found, and tags not matching the
+// pattern are kept.
+type pullRequestPolicyProcessor struct {
+	policy.BaseProcessor
+	pattern *regexp.Regexp
+	now     func() time.Time
+	pulls   func(number int) (*pullRequest, error)
+}
+
+// Ensure (*pullRequestPolicyProcessor) implements interface Processor
+var _ policy.Processor = (*pullRequestPolicyProcessor)(nil)
+
+// GetPolicyType gets policy type.
+func (p *pullRequestPolicyProcessor) GetPolicyType() policy.Type {
+	return policy.PullRequestPolicy
+}
+
+// Validate validates the policy configuration, it compiles the tag pattern.
+func (p *pullRequestPolicyProcessor) Validate() error {
+	cfg := p.Cfg.Policy.PullRequestPolicy
+	if cfg == nil {
+		return fmt.Errorf("policy.pullRequestPolicy not configured, it's necessary when policy.type == 'pullRequest'")
+	}
+
+	switch cfg.Provider {
+	case "", ProviderGitHub, ProviderGitLab:
+	default:
+		return fmt.Errorf("unsupported policy.pullRequestPolicy.provider '%s', should be '%s' or '%s'", cfg.Provider, ProviderGitHub, ProviderGitLab)
+	}
+
+	if len(cfg.BaseURL) == 0 || len(cfg.Repo) == 0 {
+		return fmt.Errorf("policy.pullRequestPolicy.baseURL and policy.pullRequestPolicy.repo should not be empty")
+	}
+
+	if cfg.GracePeriod < 0 {
+		return fmt.Errorf("policy.pullRequestPolicy.gracePeriod should not be negative")
+	}
+
+	expr := cfg.TagPattern
+	if len(expr) == 0 {
+		expr = DefaultTagPattern
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("invalid policy.pullRequestPolicy.tagPattern: %v", err)
+	}
+	found := false
+	for _, name := range pattern.SubexpNames() {
+		if name == "pr" {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("policy.pullRequestPolicy.tagPattern '%s' should have named capture 'pr'", expr)
+	}
+	p.pattern = pattern
+
+	return nil
+}
+
+// ListCandidates list all candidates to be remove based on the policy
+func (p *pullRequestPolicyProcessor) ListCandidates() ([]*policy.Candidate, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	images, err := p.ListTags()
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := p.Evaluate(images)
+	if err != nil {
+		return nil, err
+	}
+
+	return policy.BuildCandidates(images, selected, p.Cfg.Policy.RetainTags), nil
+}
+
+// Evaluate selects tags to remove from the given repos. Each pull request is queried only once.
+func (p *pullRequestPolicyProcessor) Evaluate(images []*policy.RepoTags) (policy.Selection, error) {
+	cfg := p.Cfg.Policy.PullRequestPolicy
+	deadline := p.now().Add(-cfg.GracePeriod.Duration())
+
+	pulls := make(map[int]*pullRequest)
+	selected := make(policy.Selection)
+	for _, r := range images {
+		for _, t := range r.Tags {
+			number, ok := p.parse(t.Name)
+			if !ok {
+				continue
+			}
+
+			pr, queried := pulls[number]
+			if !queried {
+				var err error
+				pr, err = p.pulls(number)
+				if err == errNotFound {
+					logrus.Warningf("Pull request %d of tag %s/%s:%s not found, keep it", number, r.Project, r.Repo, t.Name)
+				} else if err != nil {
+					return nil, err
+				}
+				pulls[number] = pr
+			}
+
+			if pr == nil || pr.Open || pr.ClosedAt.After(deadline) {
+				continue
+			}
+			selected.Add(r, t.Name)
+		}
+	}
+
+	return selected, nil
+}
+
+// parse gets pull request number from tag name.
+func (p *pullRequestPolicyProcessor) parse(tag string) (int, bool) {
+	m := p.pattern.FindStringSubmatch(tag)
+	if m == nil {
+		return 0, false
+	}
+
+	for i, name := range p.pattern.SubexpNames() {
+		if name == "pr" {
+			number, err := strconv.Atoi(m[i])
+			return number, err == nil
+		}
+	}
+
+	return 0, false
+}
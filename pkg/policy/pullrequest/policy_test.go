@@ -0,0 +1,123 @@
+package pullrequest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+func TestAPI(t *testing.T) {
+	closed := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.EscapedPath() == "/repos/owner/app/pulls/1" && r.Header.Get("Authorization") == "token secret":
+			fmt.Fprint(w, `{"state": "open", "closed_at": null, "merged_at": null}`)
+		case r.URL.EscapedPath() == "/repos/owner/app/pulls/2":
+			fmt.Fprintf(w, `{"state": "closed", "closed_at": "%s", "merged_at": "%s"}`, closed.Format(time.RFC3339), closed.Add(-time.Second).Format(time.RFC3339))
+		case r.URL.EscapedPath() == "/projects/group%2Fapp/merge_requests/3" && r.Header.Get("PRIVATE-TOKEN") == "secret":
+			fmt.Fprintf(w, `{"state": "merged", "closed_at": null, "merged_at": "%s"}`, closed.Format(time.RFC3339))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	pr, err := newAPI("", server.URL, "owner/app", "secret").get(1)
+	assert.Nil(t, err)
+	assert.True(t, pr.Open)
+
+	pr, err = newAPI(ProviderGitHub, server.URL+"/", "owner/app", "").get(2)
+	assert.Nil(t, err)
+	assert.False(t, pr.Open)
+	assert.True(t, closed.Equal(pr.ClosedAt))
+
+	pr, err = newAPI(ProviderGitLab, server.URL, "group/app", "secret").get(3)
+	assert.Nil(t, err)
+	assert.False(t, pr.Open)
+	assert.True(t, closed.Equal(pr.ClosedAt))
+
+	_, err = newAPI(ProviderGitHub, server.URL, "owner/app", "").get(4)
+	assert.Equal(t, errNotFound, err)
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Now()
+	repo := &policy.RepoTags{
+		Project: "library",
+		Repo:    "app",
+		Tags: []policy.Tag{
+			{Name: "pr-1"},
+			{Name: "pr-2-abcdef0"},
+			{Name: "pr-3"},
+			{Name: "pr-4"},
+			{Name: "build-3"},
+			{Name: "latest"},
+		},
+	}
+	pulls := map[int]*pullRequest{
+		1: {Open: true},
+		2: {ClosedAt: now.Add(-time.Hour * 48)},
+		3: {ClosedAt: now.Add(-time.Hour)},
+	}
+
+	cases := []struct {
+		policy   config.PullRequestPolicy
+		expected []string
+	}{
+		{
+			config.PullRequestPolicy{},
+			[]string{"pr-2-abcdef0", "pr-3"},
+		},
+		{
+			config.PullRequestPolicy{GracePeriod: config.Duration(time.Hour * 24)},
+			[]string{"pr-2-abcdef0"},
+		},
+		{
+			config.PullRequestPolicy{TagPattern: `^(pr|build)-(?P<pr>\d+)$`},
+			[]string{"pr-3", "build-3"},
+		},
+	}
+
+	for _, c := range cases {
+		c.policy.BaseURL = "https://api.github.com"
+		c.policy.Repo = "owner/app"
+		p := newFactory()(config.C{Policy: config.Policy{PullRequestPolicy: &c.policy}}).(*pullRequestPolicyProcessor)
+		p.now = func() time.Time { return now }
+		queried := make(map[int]int)
+		p.pulls = func(number int) (*pullRequest, error) {
+			queried[number]++
+			if pr, ok := pulls[number]; ok {
+				return pr, nil
+			}
+			return nil, errNotFound
+		}
+		assert.Nil(t, p.Validate())
+		selected, err := p.Evaluate([]*policy.RepoTags{repo})
+		assert.Nil(t, err)
+
+		var names []string
+		for _, tag := range repo.Tags {
+			if selected.Has(repo, tag.Name) {
+				names = append(names, tag.Name)
+			}
+		}
+		assert.Equal(t, c.expected, names)
+		for number, n := range queried {
+			assert.Equal(t, 1, n, "pull request %d queried more than once", number)
+		}
+	}
+
+	p := newFactory()(config.C{Policy: config.Policy{PullRequestPolicy: &config.PullRequestPolicy{
+		BaseURL:    "https://api.github.com",
+		Repo:       "owner/app",
+		TagPattern: `^pr-(\d+)$`,
+	}}}).(*pullRequestPolicyProcessor)
+	assert.NotNil(t, p.Validate())
+}
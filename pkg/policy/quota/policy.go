@@ -0,0 +1,232 @@
+package quota
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+func init() {
+	policy.RegisterProcessorFactory(policy.QuotaPolicy, newFactory())
+}
+
+func newFactory() func(cfg config.C) policy.Processor {
+	return func(cfg config.C) policy.Processor {
+		p := &quotaPolicyProcessor{
+			BaseProcessor: policy.BaseProcessor{
+				Client: harbor.APIClient,
+				Cfg:    cfg,
+			},
+			now: time.Now,
+		}
+		p.quotas = func() ([]*harbor.Quota, error) {
+			return p.Client.AllQuotas()
+		}
+		p.pulls = func(startTime, endTime int64) ([]*harbor.AccessLog, error) {
+			return p.ListAccessLogs(startTime, endTime, harbor.AccessOperationPull)
+		}
+		return p
+	}
+}
+
+// quotaPolicyProcessor cleans projects whose storage usage exceeds the high-water mark of the quota.
+// Tags are removed in order of last use, which is the later of the last pull and the creation, until
+// the projected usage drops below the low-water mark. Projected usage is estimated by sizes of images
+// no longer referenced by any tag in the project.
+type quotaPolicyProcessor struct {
+	policy.BaseProcessor
+	now    func() time.Time
+	quotas func() ([]*harbor.Quota, error)
+	pulls  func(startTime, endTime int64) ([]*harbor.AccessLog, error)
+}
+
+// item is a tag in a repo that can be removed.
+type item struct {
+	repo     *policy.RepoTags
+	tag      policy.Tag
+	lastUsed time.Time
+}
+
+// Ensure (*quotaPolicyProcessor) implements interface Processor
+var _ policy.Processor = (*quotaPolicyProcessor)(nil)
+
+// GetPolicyType gets policy type.
+func (p *quotaPolicyProcessor) GetPolicyType() policy.Type {
+	return policy.QuotaPolicy
+}
+
+// Validate validates the policy configuration.
+func (p *quotaPolicyProcessor) Validate() error {
+	cfg := p.Cfg.Policy.QuotaPolicy
+	if cfg == nil {
+		return fmt.Errorf("policy.quotaPolicy not configured, it's necessary when policy.type == 'quota'")
+	}
+
+	if cfg.HighWater <= 0 || cfg.HighWater > 100 {
+		return fmt.Errorf("policy.quotaPolicy.highWater should be a percentage in (0, 100]")
+	}
+
+	if cfg.LowWater <= 0 || cfg.LowWater > cfg.HighWater {
+		return fmt.Errorf("policy.quotaPolicy.lowWater should be a percentage in (0, highWater]")
+	}
+
+	if cfg.RetainNumber < 0 {
+		return fmt.Errorf("policy.quotaPolicy.retainNumber should not be negative")
+	}
+
+	if cfg.PullWindow < 0 {
+		return fmt.Errorf("policy.quotaPolicy.pullWindow should not be negative")
+	}
+
+	return nil
+}
+
+// ListCandidates list all candidates to be remove based on the policy
+func (p *quotaPolicyProcessor) ListCandidates() ([]*policy.Candidate, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	images, err := p.ListTags()
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := p.Evaluate(images)
+	if err != nil {
+		return nil, err
+	}
+
+	return policy.BuildCandidates(images, selected, p.Cfg.Policy.RetainTags), nil
+}
+
+// Evaluate selects tags to remove from the given repos. Tags matching retain patterns are regarded
+// as remained when projecting the usage.
+func (p *quotaPolicyProcessor) Evaluate(images []*policy.RepoTags) (policy.Selection, error) {
+	quotas, err := p.quotas()
+	if err != nil {
+		return nil, fmt.Errorf("list quotas error: %v", err)
+	}
+	quotaOfProject := make(map[string]*harbor.Quota)
+	for _, q := range quotas {
+		quotaOfProject[q.Ref.Name] = q
+	}
+
+	pulled, err := p.pullTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	projects := make(map[string][]*policy.RepoTags)
+	var names []string
+	for _, r := range images {
+		if _, ok := projects[r.Project]; !ok {
+			names = append(names, r.Project)
+		}
+		projects[r.Project] = append(projects[r.Project], r)
+	}
+
+	selected := make(policy.Selection)
+	for _, name := range names {
+		q, ok := quotaOfProject[name]
+		if !ok || q.Hard.Storage <= 0 {
+			continue
+		}
+		if q.Used.Storage*100 <= q.Hard.Storage*int64(p.Cfg.Policy.QuotaPolicy.HighWater) {
+			continue
+		}
+
+		p.evaluateProject(projects[name], q, pulled, selected)
+	}
+
+	return selected, nil
+}
+
+// evaluateProject selects least recently used tags in the project until the projected usage drops
+// below the low-water mark.
+func (p *quotaPolicyProcessor) evaluateProject(repos []*policy.RepoTags, q *harbor.Quota, pulled map[string]time.Time, selected policy.Selection) {
+	cfg := p.Cfg.Policy.QuotaPolicy
+
+	refs := make(map[string]int)
+	sizes := make(map[string]int64)
+	var removable []item
+	for _, r := range repos {
+		tags := make([]policy.Tag, len(r.Tags))
+		copy(tags, r.Tags)
+		sort.SliceStable(tags, func(i, j int) bool {
+			return tags[i].Created.After(tags[j].Created)
+		})
+
+		retained := make(map[string]bool)
+		for i, t := range tags {
+			refs[t.Digest]++
+			sizes[t.Digest] = t.Size
+			if i < cfg.RetainNumber || policy.Retain(p.Cfg.Policy.RetainTags, t.Name) {
+				retained[t.Digest] = true
+			}
+		}
+
+		// Tags sharing image with retained tags release nothing, so they are not removed.
+		for _, t := range tags {
+			if retained[t.Digest] {
+				continue
+			}
+
+			lastUsed := t.Created
+			if pullTime, ok := pulled[fmt.Sprintf("%s/%s:%s", r.Project, r.Repo, t.Name)]; ok && pullTime.After(lastUsed) {
+				lastUsed = pullTime
+			}
+			removable = append(removable, item{repo: r, tag: t, lastUsed: lastUsed})
+		}
+	}
+
+	sort.SliceStable(removable, func(i, j int) bool {
+		if !removable[i].lastUsed.Equal(removable[j].lastUsed) {
+			return removable[i].lastUsed.Before(removable[j].lastUsed)
+		}
+		return removable[i].tag.Created.Before(removable[j].tag.Created)
+	})
+
+	projected := q.Used.Storage
+	for _, it := range removable {
+		if projected*100 < q.Hard.Storage*int64(cfg.LowWater) {
+			break
+		}
+		selected.Add(it.repo, it.tag.Name)
+		refs[it.tag.Digest]--
+		if refs[it.tag.Digest] == 0 {
+			projected -= sizes[it.tag.Digest]
+		}
+	}
+}
+
+// pullTimes gets last pull time of tags within the pull window, it's keyed by 'project/repo:tag'.
+func (p *quotaPolicyProcessor) pullTimes() (map[string]time.Time, error) {
+	pulled := make(map[string]time.Time)
+	window := p.Cfg.Policy.QuotaPolicy.PullWindow
+	if window <= 0 {
+		return pulled, nil
+	}
+
+	endTime := p.now().Unix()
+	logs, err := p.pulls(endTime-window.Seconds(), endTime)
+	if err != nil {
+		return nil, fmt.Errorf("list pull logs error: %v", err)
+	}
+
+	for _, log := range logs {
+		if log.Operation != harbor.AccessOperationPull {
+			continue
+		}
+		key := fmt.Sprintf("%s:%s", log.RepoName, log.Tag)
+		if log.OpTime.After(pulled[key]) {
+			pulled[key] = log.OpTime
+		}
+	}
+
+	return pulled, nil
+}
@@ -0,0 +1,75 @@
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Now()
+	day := time.Hour * 24
+	app := &policy.RepoTags{
+		Project: "dev",
+		Repo:    "app",
+		Tags: []policy.Tag{
+			{Name: "v4", Digest: "d4", Size: 40, Created: now.Add(-day)},
+			{Name: "v3", Digest: "d3", Size: 30, Created: now.Add(-day * 2)},
+			{Name: "v2", Digest: "d2", Size: 20, Created: now.Add(-day * 3)},
+			{Name: "v1", Digest: "d1", Size: 10, Created: now.Add(-day * 4)},
+			{Name: "stable", Digest: "d1", Size: 10, Created: now.Add(-day * 4)},
+		},
+	}
+	tool := &policy.RepoTags{
+		Project: "dev",
+		Repo:    "tool",
+		Tags: []policy.Tag{
+			{Name: "t2", Digest: "t2", Size: 50, Created: now.Add(-day * 5)},
+			{Name: "t1", Digest: "t1", Size: 50, Created: now.Add(-day * 6)},
+		},
+	}
+	other := &policy.RepoTags{
+		Project: "ops",
+		Repo:    "app",
+		Tags: []policy.Tag{
+			{Name: "o1", Digest: "o1", Size: 50, Created: now.Add(-day * 10)},
+		},
+	}
+	images := []*policy.RepoTags{app, tool, other}
+
+	cfg := config.C{Policy: config.Policy{
+		QuotaPolicy: &config.QuotaPolicy{HighWater: 85, LowWater: 70, RetainNumber: 1, PullWindow: config.Duration(day * 30)},
+		RetainTags:  []string{"stable"},
+	}}
+	p := newFactory()(cfg).(*quotaPolicyProcessor)
+	p.quotas = func() ([]*harbor.Quota, error) {
+		return []*harbor.Quota{
+			{Ref: harbor.QuotaRef{Name: "dev"}, Hard: harbor.QuotaResources{Storage: 200}, Used: harbor.QuotaResources{Storage: 180}},
+			{Ref: harbor.QuotaRef{Name: "ops"}, Hard: harbor.QuotaResources{Storage: 200}, Used: harbor.QuotaResources{Storage: 50}},
+		}, nil
+	}
+	p.pulls = func(startTime, endTime int64) ([]*harbor.AccessLog, error) {
+		return []*harbor.AccessLog{
+			{RepoName: "dev/tool", Tag: "t1", Operation: harbor.AccessOperationPull, OpTime: now.Add(-time.Hour)},
+		}, nil
+	}
+	assert.Nil(t, p.Validate())
+
+	selected, err := p.Evaluate(images)
+	assert.Nil(t, err)
+
+	var names []string
+	for _, r := range images {
+		for _, tag := range r.Tags {
+			if selected.Has(r, tag.Name) {
+				names = append(names, tag.Name)
+			}
+		}
+	}
+	assert.Equal(t, []string{"v3", "v2"}, names)
+}
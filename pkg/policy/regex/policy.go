@@ -39,69 +39,58 @@ func (p *regexPolicyProcessor) GetPolicyType() policy.Type {
 	return policy.RegexPolicy
 }
 
-// ListCandidates list all candidates to be remove based on the policy
-func (p *regexPolicyProcessor) ListCandidates() ([]*policy.Candidate, error) {
-	images, err := p.ListTags()
-	if err != nil {
-		return nil, err
-	}
-
+// Validate validates the policy configuration.
+func (p *regexPolicyProcessor) Validate() error {
 	if p.Cfg.Policy.RegexPolicy == nil {
-		return nil, fmt.Errorf("policy.regexPolicy not configured")
+		return fmt.Errorf("policy.regexPolicy not configured")
 	}
 
 	if len(p.Cfg.Policy.RegexPolicy.Repos) == 0 {
-		return nil, fmt.Errorf("policy.regexPolicy.repos is empty, nothing will be cleaned, you may want '.*'")
+		return fmt.Errorf("policy.regexPolicy.repos is empty, nothing will be cleaned, you may want '.*'")
 	}
 
 	if len(p.Cfg.Policy.RegexPolicy.Tags) == 0 {
-		return nil, fmt.Errorf("policy.regexPolicy.tags is empty, nothing will be cleaned, you may want '.*'")
+		return fmt.Errorf("policy.regexPolicy.tags is empty, nothing will be cleaned, you may want '.*'")
+	}
+
+	return p.compileRegex()
+}
+
+// ListCandidates list all candidates to be remove based on the policy
+func (p *regexPolicyProcessor) ListCandidates() ([]*policy.Candidate, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	images, err := p.ListTags()
+	if err != nil {
+		return nil, err
 	}
 
-	if err := p.compileRegex(); err != nil {
+	selected, err := p.Evaluate(images)
+	if err != nil {
 		return nil, err
 	}
 
-	var imagesToClean []*policy.Candidate
+	return policy.BuildCandidates(images, selected, p.Cfg.Policy.RetainTags), nil
+}
+
+// Evaluate selects tags to remove from the given repos
+func (p *regexPolicyProcessor) Evaluate(images []*policy.RepoTags) (policy.Selection, error) {
+	selected := make(policy.Selection)
 	for _, r := range images {
 		if !p.matchRepo(r.Repo) {
 			continue
 		}
 
-		var candidates []policy.Tag
-		var remains []policy.Tag
 		for _, t := range r.Tags {
-			if p.matchTag(t.Name) && !policy.Retain(p.Cfg.Policy.RetainTags, t.Name) {
-				candidates = append(candidates, t)
-				continue
+			if p.matchTag(t.Name) {
+				selected.Add(r, t.Name)
 			}
-
-			remains = append(remains, t)
-		}
-
-		remainsDigests := make(map[string][]string)
-		for _, t := range remains {
-			remainsDigests[t.Digest] = append(remainsDigests[t.Digest], t.Name)
-		}
-
-		dangerTags := make(map[string][]string)
-		for _, t := range candidates {
-			if tags, ok := remainsDigests[t.Digest]; ok {
-				dangerTags[t.Digest] = tags
-			}
-		}
-
-		if len(candidates) > 0 {
-			imagesToClean = append(imagesToClean, &policy.Candidate{
-				Project:   r.Project,
-				Repo:      r.Repo,
-				Tags:      candidates,
-				Protected: dangerTags,
-			})
 		}
 	}
 
-	return imagesToClean, nil
+	return selected, nil
 }
 
 func normalizeRegex(regex string) string {
@@ -117,6 +106,8 @@ func normalizeRegex(regex string) string {
 }
 
 func (p *regexPolicyProcessor) compileRegex() error {
+	p.repoPatterns = nil
+	p.tagPatterns = nil
 	for _, repoRegex := range p.Cfg.Policy.RegexPolicy.Repos {
 		r, e := regexp.Compile(normalizeRegex(repoRegex))
 		if e != nil {
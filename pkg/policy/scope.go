@@ -0,0 +1,147 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/goharbor/harbor/src/common/utils"
+)
+
+// Scope selects projects and repos to clean. Projects are selected by project name patterns, all
+// repos in them are selected. Repos are selected by 'project/repo' patterns. Patterns are globs,
+// '*' matches any sequence of characters including '/', '?' matches any single character. An empty
+// scope selects everything.
+type Scope struct {
+	Projects []string
+	Repos    []string
+}
+
+// NewScope creates a scope from project patterns and 'project/repo' patterns.
+func NewScope(projects, repos []string) (*Scope, error) {
+	for _, r := range repos {
+		if p, repo := utils.ParseRepository(r); len(p) == 0 || len(repo) == 0 {
+			return nil, fmt.Errorf("invalid repo selector '%s', 'project/repo' expected", r)
+		}
+	}
+
+	return &Scope{
+		Projects: projects,
+		Repos:    repos,
+	}, nil
+}
+
+// All checks whether the scope selects everything.
+func (s *Scope) All() bool {
+	return len(s.Projects) == 0 && len(s.Repos) == 0
+}
+
+// ProjectPatterns gets patterns of projects that may have repos selected.
+func (s *Scope) ProjectPatterns() []string {
+	patterns := append([]string{}, s.Projects...)
+	for _, r := range s.Repos {
+		p, _ := utils.ParseRepository(r)
+		patterns = append(patterns, p)
+	}
+
+	return patterns
+}
+
+// MatchProject checks whether the project has any repo selected.
+func (s *Scope) MatchProject(project string) bool {
+	return s.All() || MatchAny(s.ProjectPatterns(), project)
+}
+
+// WholeProject checks whether all repos in the project are selected.
+func (s *Scope) WholeProject(project string) bool {
+	return s.All() || MatchAny(s.Projects, project)
+}
+
+// MatchRepo checks whether the repo is selected.
+func (s *Scope) MatchRepo(project, repo string) bool {
+	return s.WholeProject(project) || MatchAny(s.Repos, project+"/"+repo)
+}
+
+// LiteralRepos gets repos of the project selected by patterns without glob characters, they can
+// be queried directly without listing all repos of the project. If any glob pattern may match repos
+// of the project, false is returned.
+func (s *Scope) LiteralRepos(project string) ([]string, bool) {
+	if s.WholeProject(project) {
+		return nil, false
+	}
+
+	var repos []string
+	for _, r := range s.Repos {
+		p, repo := utils.ParseRepository(r)
+		if !MatchGlob(p, project) {
+			continue
+		}
+		if IsGlob(r) {
+			return nil, false
+		}
+		repos = append(repos, repo)
+	}
+
+	return repos, true
+}
+
+// IsGlob checks whether the pattern contains glob characters.
+func IsGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?")
+}
+
+// MatchAny checks whether the name matches any of the glob patterns.
+func MatchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if MatchGlob(pattern, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var (
+	globLock sync.Mutex
+	globs    = make(map[string]*regexp.Regexp)
+)
+
+// MatchGlob checks whether the name matches the glob pattern, '*' matches any sequence of characters
+// including '/', '?' matches any single character.
+func MatchGlob(pattern, name string) bool {
+	if !IsGlob(pattern) {
+		return pattern == name
+	}
+
+	return compileGlob(pattern).MatchString(name)
+}
+
+// compileGlob compiles the glob pattern to regex, compiled regexes are cached as the same patterns
+// are matched against every project, repo and tag.
+func compileGlob(pattern string) *regexp.Regexp {
+	globLock.Lock()
+	defer globLock.Unlock()
+
+	if re, ok := globs[pattern]; ok {
+		return re
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	for _, c := range pattern {
+		switch c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	re := regexp.MustCompile(b.String())
+	globs[pattern] = re
+	return re
+}
@@ -0,0 +1,84 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+)
+
+func TestMatchGlob(t *testing.T) {
+	assert.True(t, MatchGlob("library", "library"))
+	assert.False(t, MatchGlob("library", "library2"))
+	assert.True(t, MatchGlob("ci-*", "ci-tools"))
+	assert.True(t, MatchGlob("release/*", "release/devops/tools"))
+	assert.True(t, MatchGlob("release/?evops/*", "release/devops/tools"))
+	assert.False(t, MatchGlob("release/*", "releases/tools"))
+	assert.False(t, MatchGlob("a.b", "aab"))
+}
+
+func TestScope(t *testing.T) {
+	_, err := NewScope(nil, []string{"busybox"})
+	assert.NotNil(t, err)
+
+	scope, err := NewScope([]string{"ci-*"}, []string{"library/busybox", "release/devops/*"})
+	assert.Nil(t, err)
+
+	assert.True(t, scope.MatchProject("ci-tools"))
+	assert.True(t, scope.MatchProject("library"))
+	assert.False(t, scope.MatchProject("sandbox"))
+
+	assert.True(t, scope.MatchRepo("ci-tools", "builder"))
+	assert.True(t, scope.MatchRepo("library", "busybox"))
+	assert.False(t, scope.MatchRepo("library", "alpine"))
+	assert.True(t, scope.MatchRepo("release", "devops/tools"))
+	assert.False(t, scope.MatchRepo("release", "web"))
+
+	repos, ok := scope.LiteralRepos("library")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"busybox"}, repos)
+	_, ok = scope.LiteralRepos("release")
+	assert.False(t, ok)
+	_, ok = scope.LiteralRepos("ci-tools")
+	assert.False(t, ok)
+
+	all, _ := NewScope(nil, nil)
+	assert.True(t, all.All())
+	assert.True(t, all.MatchRepo("any", "repo"))
+}
+
+func TestMatchScope(t *testing.T) {
+	scoped := []*config.ScopedPolicy{
+		{Name: "release", Projects: []string{"release"}},
+		{Projects: []string{"ci-*"}, Repos: []string{"ci-*/builder/*"}},
+		{Projects: []string{"*"}, Repos: []string{"sandbox/*"}},
+	}
+
+	assert.Equal(t, 0, matchScope(scoped, &RepoTags{Project: "release", Repo: "app"}))
+	assert.Equal(t, 1, matchScope(scoped, &RepoTags{Project: "ci-tools", Repo: "builder/go"}))
+	assert.Equal(t, 3, matchScope(scoped, &RepoTags{Project: "ci-tools", Repo: "runner"}))
+	// Repo patterns match 'project/repo', not the bare repo name
+	assert.Equal(t, 2, matchScope(scoped, &RepoTags{Project: "sandbox", Repo: "app"}))
+	assert.Equal(t, 3, matchScope(scoped, &RepoTags{Project: "library", Repo: "sandbox/app"}))
+	assert.Equal(t, "release", scopeName(scoped, 0))
+	assert.Equal(t, "scopedPolicies[1]", scopeName(scoped, 1))
+	assert.Equal(t, GlobalScope, scopeName(scoped, 3))
+
+	err := validateScopedPolicies(config.C{ScopedPolicies: []*config.ScopedPolicy{{Projects: []string{"ci"}, Repos: []string{"builder"}}}})
+	assert.NotNil(t, err)
+}
+
+func TestScopePolicy(t *testing.T) {
+	cfg := config.C{
+		Policy: config.Policy{Type: "number", RetainTags: []string{"latest"}},
+		ScopedPolicies: []*config.ScopedPolicy{
+			{Projects: []string{"ci"}, Policy: config.Policy{Type: "size", RetainTags: []string{"stable-*"}}},
+		},
+	}
+
+	assert.Equal(t, []string{"latest", "stable-*"}, scopePolicy(cfg, 0).RetainTags)
+	assert.Equal(t, []string{"stable-*"}, cfg.ScopedPolicies[0].Policy.RetainTags)
+	assert.Equal(t, "number", scopePolicy(cfg, 1).Type)
+	assert.Equal(t, []string{"latest"}, scopePolicy(cfg, 1).RetainTags)
+}
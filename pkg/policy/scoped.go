@@ -0,0 +1,195 @@
+package policy
+
+import (
+	"fmt"
+
+	"github.com/goharbor/harbor/src/common/utils"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+)
+
+// GlobalScope is name of the scope when the global policy applies.
+const GlobalScope = "global"
+
+// ListCandidates lists candidates to remove according to the config. Without scoped policies, label
+// selector and filters, it's done by processor of the global policy. Otherwise tags are listed once,
+// each repo is assigned to the first scoped policy it matches or the global policy, and evaluated by
+// that policy.
+func ListCandidates(cfg config.C) ([]*Candidate, error) {
+	SetLogRetention(cfg)
+
+	if len(cfg.ScopedPolicies) == 0 && !filtered(cfg.Policy) {
+		factory := GetProcessorFactory(Type(cfg.Policy.Type))
+		if factory == nil {
+			return nil, fmt.Errorf("no processor factory found for policy type: %s", cfg.Policy.Type)
+		}
+
+		candidates, err := factory(cfg).ListCandidates()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range candidates {
+			c.Scope = GlobalScope
+		}
+		return candidates, nil
+	}
+
+	evaluators, err := scopedEvaluators(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	base := &BaseProcessor{Cfg: cfg, Client: harbor.APIClient}
+	images, err := base.ListTags()
+	if err != nil {
+		return nil, err
+	}
+
+	// Group repos by scope, index len(cfg.ScopedPolicies) is for the global policy.
+	groups := make([][]*RepoTags, len(evaluators))
+	for _, r := range images {
+		i := matchScope(cfg.ScopedPolicies, r)
+		groups[i] = append(groups[i], r)
+	}
+
+	var candidates []*Candidate
+	for i, repos := range groups {
+		if len(repos) == 0 {
+			continue
+		}
+
+		selected, err := evaluators[i].Evaluate(repos)
+		if err != nil {
+			return nil, fmt.Errorf("evaluate policy of scope '%s' error: %v", scopeName(cfg.ScopedPolicies, i), err)
+		}
+
+		for _, c := range BuildCandidates(repos, selected, scopePolicy(cfg, i).RetainTags) {
+			c.Scope = scopeName(cfg.ScopedPolicies, i)
+			candidates = append(candidates, c)
+		}
+	}
+
+	return candidates, nil
+}
+
+// scopedEvaluators creates evaluators for all scoped policies and the global policy (the last one).
+func scopedEvaluators(cfg config.C) ([]Evaluator, error) {
+	var evaluators []Evaluator
+	for i := range cfg.ScopedPolicies {
+		e, err := NewEvaluator(cfg, scopePolicy(cfg, i))
+		if err != nil {
+			return nil, fmt.Errorf("scope '%s': %v", scopeName(cfg.ScopedPolicies, i), err)
+		}
+		evaluators = append(evaluators, e)
+	}
+
+	e, err := NewEvaluator(cfg, cfg.Policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(evaluators, e), nil
+}
+
+// NewEvaluator creates a validated evaluator for the policy, other parts of the config are kept.
+func NewEvaluator(cfg config.C, p config.Policy) (Evaluator, error) {
+	factory := GetProcessorFactory(Type(p.Type))
+	if factory == nil {
+		return nil, fmt.Errorf("no processor factory found for policy type: %s", p.Type)
+	}
+
+	cfg.Policy = p
+	processor := factory(cfg)
+	if v, ok := processor.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	e, ok := processor.(Evaluator)
+	if !ok {
+		return nil, fmt.Errorf("policy type %s doesn't support evaluation on given tags", p.Type)
+	}
+
+	if len(p.LabelSelector) > 0 {
+		selector, err := ParseLabelSelector(p.LabelSelector)
+		if err != nil {
+			return nil, err
+		}
+		e = &labelScopedEvaluator{selector: selector, evaluator: e}
+	}
+
+	if p.Filters != nil {
+		if err := ValidateFilters(p.Filters); err != nil {
+			return nil, err
+		}
+		e = &filteredEvaluator{filters: p.Filters, evaluator: e}
+	}
+
+	return e, nil
+}
+
+// scopePolicy gets policy of the scope at index i, or the global policy if i is len(cfg.ScopedPolicies).
+// Retain tags of a scoped policy are merged with the global ones, they're the retain tags applied to
+// the result, so that policies targeting storage usage don't count retained tags as freed.
+func scopePolicy(cfg config.C, i int) config.Policy {
+	if i >= len(cfg.ScopedPolicies) {
+		return cfg.Policy
+	}
+
+	p := cfg.ScopedPolicies[i].Policy
+	p.RetainTags = append(append([]string{}, cfg.Policy.RetainTags...), p.RetainTags...)
+	return p
+}
+
+// matchScope gets index of the first scoped policy the repo matches, or len(scoped) if none matches.
+func matchScope(scoped []*config.ScopedPolicy, r *RepoTags) int {
+	for i, s := range scoped {
+		if !MatchAny(s.Projects, r.Project) {
+			continue
+		}
+		if len(s.Repos) > 0 && !MatchAny(s.Repos, r.Project+"/"+r.Repo) {
+			continue
+		}
+		return i
+	}
+
+	return len(scoped)
+}
+
+func scopeName(scoped []*config.ScopedPolicy, i int) string {
+	if i >= len(scoped) {
+		return GlobalScope
+	}
+	if len(scoped[i].Name) > 0 {
+		return scoped[i].Name
+	}
+	return fmt.Sprintf("scopedPolicies[%d]", i)
+}
+
+// filtered checks whether the policy is scoped to images by label selector or filters.
+func filtered(p config.Policy) bool {
+	return len(p.LabelSelector) > 0 || p.Filters != nil
+}
+
+// validateScopedPolicies validates all scoped policies, label selectors and filters.
+func validateScopedPolicies(cfg config.C) error {
+	for i, scoped := range cfg.ScopedPolicies {
+		if len(scoped.Projects) == 0 {
+			return fmt.Errorf("scope '%s': projects should not be empty, use '*' to match all", scopeName(cfg.ScopedPolicies, i))
+		}
+		for _, r := range scoped.Repos {
+			if p, repo := utils.ParseRepository(r); len(p) == 0 || len(repo) == 0 {
+				return fmt.Errorf("scope '%s': invalid repo pattern '%s', 'project/repo' expected", scopeName(cfg.ScopedPolicies, i), r)
+			}
+		}
+	}
+
+	if len(cfg.ScopedPolicies) > 0 || filtered(cfg.Policy) {
+		_, err := scopedEvaluators(cfg)
+		return err
+	}
+
+	return nil
+}
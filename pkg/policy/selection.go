@@ -0,0 +1,94 @@
+package policy
+
+// Evaluator is implemented by processors that can select tags to remove from a given tag inventory,
+// so that several policies can be evaluated against a single fetch of tags. Tags selected are not
+// filtered by retain patterns, which are applied when building candidates.
+type Evaluator interface {
+	// Evaluate selects tags to remove from the given repos.
+	Evaluate(repos []*RepoTags) (Selection, error)
+}
+
+// Selection holds tags selected by a policy, it maps 'project/repo' to set of tag names.
+type Selection map[string]map[string]bool
+
+func selectionKey(r *RepoTags) string {
+	return r.Project + "/" + r.Repo
+}
+
+// Add adds a tag of the repo to the selection.
+func (s Selection) Add(r *RepoTags, tag string) {
+	key := selectionKey(r)
+	if _, ok := s[key]; !ok {
+		s[key] = make(map[string]bool)
+	}
+	s[key][tag] = true
+}
+
+// Has checks whether a tag of the repo is selected.
+func (s Selection) Has(r *RepoTags, tag string) bool {
+	return s[selectionKey(r)][tag]
+}
+
+// Intersect gets tags selected in both selections.
+func (s Selection) Intersect(repos []*RepoTags, other Selection) Selection {
+	result := make(Selection)
+	for _, r := range repos {
+		for _, t := range r.Tags {
+			if s.Has(r, t.Name) && other.Has(r, t.Name) {
+				result.Add(r, t.Name)
+			}
+		}
+	}
+	return result
+}
+
+// Union gets tags selected in any of the selections.
+func (s Selection) Union(repos []*RepoTags, other Selection) Selection {
+	result := make(Selection)
+	for _, r := range repos {
+		for _, t := range r.Tags {
+			if s.Has(r, t.Name) || other.Has(r, t.Name) {
+				result.Add(r, t.Name)
+			}
+		}
+	}
+	return result
+}
+
+// Complement gets tags in the repos that are not selected.
+func (s Selection) Complement(repos []*RepoTags) Selection {
+	result := make(Selection)
+	for _, r := range repos {
+		for _, t := range r.Tags {
+			if !s.Has(r, t.Name) {
+				result.Add(r, t.Name)
+			}
+		}
+	}
+	return result
+}
+
+// BuildCandidates builds candidates from the repos and tags selected to remove. Tags that match
+// the retain patterns are never removed, and tags remained that share digest with tags to remove
+// are protected.
+func BuildCandidates(repos []*RepoTags, selected Selection, retainTags []string) []*Candidate {
+	var imagesToClean []*Candidate
+	for _, r := range repos {
+		var candidates []Tag
+		var remains []Tag
+		for _, t := range r.Tags {
+			if selected.Has(r, t.Name) && !Retain(retainTags, t.Name) {
+				candidates = append(candidates, t)
+				continue
+			}
+
+			remains = append(remains, t)
+		}
+
+		if c := NewCandidate(r, candidates, remains); c != nil {
+			imagesToClean = append(imagesToClean, c)
+		}
+	}
+
+	return imagesToClean
+}
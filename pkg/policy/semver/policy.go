@@ -0,0 +1,182 @@
+package semver
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+	sv "github.com/cd1989/harbor-cleaner/pkg/semver"
+)
+
+// Release line granularity
+const (
+	LineMajor = "major"
+	LineMinor = "minor"
+)
+
+// DefaultKeepPreRelease is the default number of newest pre-releases to retain per line
+const DefaultKeepPreRelease = 1
+
+// Rules for tags that are not semantic versions
+const (
+	NonSemverKeep   = "keep"
+	NonSemverDelete = "delete"
+)
+
+func init() {
+	policy.RegisterProcessorFactory(policy.SemverPolicy, newFactory())
+}
+
+func newFactory() func(cfg config.C) policy.Processor {
+	return func(cfg config.C) policy.Processor {
+		return &semverPolicyProcessor{
+			BaseProcessor: policy.BaseProcessor{
+				Client: harbor.APIClient,
+				Cfg:    cfg,
+			},
+		}
+	}
+}
+
+type semverPolicyProcessor struct {
+	policy.BaseProcessor
+}
+
+// versionedTag is a tag with its parsed semantic version
+type versionedTag struct {
+	tag     policy.Tag
+	version *sv.Version
+}
+
+// Ensure (*semverPolicyProcessor) implements interface Processor
+var _ policy.Processor = (*semverPolicyProcessor)(nil)
+
+// GetPolicyType gets policy type.
+func (p *semverPolicyProcessor) GetPolicyType() policy.Type {
+	return policy.SemverPolicy
+}
+
+// Validate validates the policy configuration.
+func (p *semverPolicyProcessor) Validate() error {
+	c := p.Cfg.Policy.SemverPolicy
+	if c == nil {
+		return fmt.Errorf("policy.semverPolicy not configured, it's necessary when policy.type == 'semver'")
+	}
+
+	if c.Line != "" && c.Line != LineMajor && c.Line != LineMinor {
+		return fmt.Errorf("policy.semverPolicy.line should be '%s' or '%s'", LineMajor, LineMinor)
+	}
+
+	if c.Keep < 1 {
+		return fmt.Errorf("policy.semverPolicy.keep should be at least 1")
+	}
+
+	if c.KeepPreRelease != nil && *c.KeepPreRelease < 0 {
+		return fmt.Errorf("policy.semverPolicy.keepPreRelease should not be negative")
+	}
+
+	if c.NonSemver != "" && c.NonSemver != NonSemverKeep && c.NonSemver != NonSemverDelete {
+		return fmt.Errorf("policy.semverPolicy.nonSemver should be '%s' or '%s'", NonSemverKeep, NonSemverDelete)
+	}
+
+	return nil
+}
+
+// ListCandidates list all candidates to be remove based on the policy
+func (p *semverPolicyProcessor) ListCandidates() ([]*policy.Candidate, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	images, err := p.ListTags()
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := p.Evaluate(images)
+	if err != nil {
+		return nil, err
+	}
+
+	return policy.BuildCandidates(images, selected, p.Cfg.Policy.RetainTags), nil
+}
+
+// Evaluate selects tags to remove from the given repos. Tags are grouped by release line, in each
+// line, releases and pre-releases beyond the newest configured numbers are selected.
+func (p *semverPolicyProcessor) Evaluate(images []*policy.RepoTags) (policy.Selection, error) {
+	c := p.Cfg.Policy.SemverPolicy
+	keepPreRelease := DefaultKeepPreRelease
+	if c.KeepPreRelease != nil {
+		keepPreRelease = *c.KeepPreRelease
+	}
+
+	selected := make(policy.Selection)
+	for _, r := range images {
+		var lines []string
+		releases := make(map[string][]versionedTag)
+		preReleases := make(map[string][]versionedTag)
+		for _, t := range r.Tags {
+			v, err := sv.Parse(t.Name)
+			if err != nil {
+				if c.NonSemver == NonSemverDelete {
+					selected.Add(r, t.Name)
+				}
+				continue
+			}
+
+			line := lineOf(v, c.Line)
+			if _, ok := releases[line]; !ok {
+				if _, ok := preReleases[line]; !ok {
+					lines = append(lines, line)
+				}
+			}
+			if v.IsPreRelease() {
+				preReleases[line] = append(preReleases[line], versionedTag{t, v})
+			} else {
+				releases[line] = append(releases[line], versionedTag{t, v})
+			}
+		}
+
+		for _, line := range lines {
+			for _, t := range beyondNewest(releases[line], c.Keep) {
+				selected.Add(r, t.Name)
+			}
+			for _, t := range beyondNewest(preReleases[line], keepPreRelease) {
+				selected.Add(r, t.Name)
+			}
+		}
+	}
+
+	return selected, nil
+}
+
+// lineOf gets release line of the version, e.g. '1' for major line, '1.2' for minor line.
+func lineOf(v *sv.Version, granularity string) string {
+	if granularity == LineMajor {
+		return fmt.Sprintf("%d", v.Major)
+	}
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// beyondNewest sorts tags by version descending, and gets tags beyond the newest n. Tags with
+// same precedence are ordered by creation time.
+func beyondNewest(tags []versionedTag, n int) []policy.Tag {
+	if len(tags) <= n {
+		return nil
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		if c := tags[i].version.Compare(tags[j].version); c != 0 {
+			return c > 0
+		}
+		return tags[i].tag.Created.After(tags[j].tag.Created)
+	})
+
+	var beyond []policy.Tag
+	for _, t := range tags[n:] {
+		beyond = append(beyond, t.tag)
+	}
+	return beyond
+}
@@ -0,0 +1,64 @@
+package semver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+func TestEvaluate(t *testing.T) {
+	zero, one := 0, 1
+	repo := &policy.RepoTags{
+		Project: "library",
+		Repo:    "app",
+		Tags: []policy.Tag{
+			{Name: "latest"},
+			{Name: "v2.1.0"},
+			{Name: "2.0.1"},
+			{Name: "2.0.0"},
+			{Name: "2.1.0-rc.2"},
+			{Name: "2.1.0-rc.1"},
+			{Name: "1.4.2"},
+			{Name: "1.4.1"},
+			{Name: "1.3.9"},
+			{Name: "1.3.8"},
+		},
+	}
+
+	cases := []struct {
+		policy   config.SemverPolicy
+		expected []string
+	}{
+		{
+			config.SemverPolicy{Line: LineMajor, Keep: 2, KeepPreRelease: &one},
+			[]string{"2.0.0", "2.1.0-rc.1", "1.3.9", "1.3.8"},
+		},
+		{
+			config.SemverPolicy{Keep: 1, KeepPreRelease: &zero, NonSemver: NonSemverDelete},
+			[]string{"latest", "2.0.0", "2.1.0-rc.2", "2.1.0-rc.1", "1.4.1", "1.3.8"},
+		},
+		// Newest pre-release per line is retained by default
+		{
+			config.SemverPolicy{Keep: 1},
+			[]string{"2.0.0", "2.1.0-rc.1", "1.4.1", "1.3.8"},
+		},
+	}
+
+	for _, c := range cases {
+		p := newFactory()(config.C{Policy: config.Policy{SemverPolicy: &c.policy}}).(*semverPolicyProcessor)
+		assert.Nil(t, p.Validate())
+		selected, err := p.Evaluate([]*policy.RepoTags{repo})
+		assert.Nil(t, err)
+
+		var names []string
+		for _, tag := range repo.Tags {
+			if selected.Has(repo, tag.Name) {
+				names = append(names, tag.Name)
+			}
+		}
+		assert.Equal(t, c.expected, names)
+	}
+}
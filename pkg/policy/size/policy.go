@@ -0,0 +1,253 @@
+package size
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+const (
+	// ScopeRepo applies the budget to each repo
+	ScopeRepo = "repo"
+	// ScopeProject applies the budget to all repos in a project
+	ScopeProject = "project"
+)
+
+func init() {
+	policy.RegisterProcessorFactory(policy.SizePolicy, newFactory())
+}
+
+func newFactory() func(cfg config.C) policy.Processor {
+	return func(cfg config.C) policy.Processor {
+		p := &sizePolicyProcessor{
+			BaseProcessor: policy.BaseProcessor{
+				Client: harbor.APIClient,
+				Cfg:    cfg,
+			},
+		}
+		p.layers = p.manifestLayers
+		return p
+	}
+}
+
+// sizePolicyProcessor removes oldest tags until a repo or a project is below the storage budget.
+type sizePolicyProcessor struct {
+	policy.BaseProcessor
+	// layers gets layers of an image by one of its tags, used when layers are deduplicated
+	layers func(project, repo, tag string) ([]*harbor.TagLayers, error)
+}
+
+// item is a tag in a repo that can be removed.
+type item struct {
+	repo *policy.RepoTags
+	tag  policy.Tag
+}
+
+// blob is a piece of storage, it's an image, or a layer when layers are deduplicated.
+type blob struct {
+	digest string
+	size   int64
+}
+
+// usage computes storage used by images remained. Images are referenced by tags and blobs are
+// referenced by images, a blob is counted only once as long as it's referenced.
+type usage struct {
+	total  int64
+	blobs  map[string][]blob
+	images map[string]int
+	refs   map[string]int
+}
+
+// Ensure (*sizePolicyProcessor) implements interface Processor
+var _ policy.Processor = (*sizePolicyProcessor)(nil)
+
+// GetPolicyType gets policy type.
+func (p *sizePolicyProcessor) GetPolicyType() policy.Type {
+	return policy.SizePolicy
+}
+
+// Validate validates the policy configuration.
+func (p *sizePolicyProcessor) Validate() error {
+	cfg := p.Cfg.Policy.SizePolicy
+	if cfg == nil {
+		return fmt.Errorf("policy.sizePolicy not configured, it's necessary when policy.type == 'size'")
+	}
+
+	if cfg.Budget <= 0 {
+		return fmt.Errorf("policy.sizePolicy.budget should be positive")
+	}
+
+	switch cfg.Scope {
+	case "", ScopeRepo, ScopeProject:
+	default:
+		return fmt.Errorf("unsupported policy.sizePolicy.scope '%s', should be one of '%s', '%s'", cfg.Scope, ScopeRepo, ScopeProject)
+	}
+
+	return nil
+}
+
+// ListCandidates list all candidates to be remove based on the policy
+func (p *sizePolicyProcessor) ListCandidates() ([]*policy.Candidate, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	images, err := p.ListTags()
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := p.Evaluate(images)
+	if err != nil {
+		return nil, err
+	}
+
+	return policy.BuildCandidates(images, selected, p.Cfg.Policy.RetainTags), nil
+}
+
+// Evaluate selects tags to remove from the given repos. Tags matching retain patterns are regarded
+// as remained when computing the usage, so that the budget is not satisfied by tags never removed.
+func (p *sizePolicyProcessor) Evaluate(images []*policy.RepoTags) (policy.Selection, error) {
+	selected := make(policy.Selection)
+	for _, repos := range p.pools(images) {
+		if err := p.evaluatePool(repos, selected); err != nil {
+			return nil, err
+		}
+	}
+
+	return selected, nil
+}
+
+// pools groups repos that share a budget.
+func (p *sizePolicyProcessor) pools(images []*policy.RepoTags) [][]*policy.RepoTags {
+	var pools [][]*policy.RepoTags
+	if p.Cfg.Policy.SizePolicy.Scope != ScopeProject {
+		for _, r := range images {
+			pools = append(pools, []*policy.RepoTags{r})
+		}
+		return pools
+	}
+
+	index := make(map[string]int)
+	for _, r := range images {
+		i, ok := index[r.Project]
+		if !ok {
+			i = len(pools)
+			index[r.Project] = i
+			pools = append(pools, nil)
+		}
+		pools[i] = append(pools[i], r)
+	}
+
+	return pools
+}
+
+// evaluatePool selects oldest tags in the repos until usage of them is below the budget. Tags of the
+// newest image in each repo are always kept.
+func (p *sizePolicyProcessor) evaluatePool(repos []*policy.RepoTags, selected policy.Selection) error {
+	u := &usage{
+		blobs:  make(map[string][]blob),
+		images: make(map[string]int),
+		refs:   make(map[string]int),
+	}
+
+	var removable []item
+	for _, r := range repos {
+		newest := -1
+		for i, t := range r.Tags {
+			if newest < 0 || t.Created.After(r.Tags[newest].Created) {
+				newest = i
+			}
+		}
+
+		for _, t := range r.Tags {
+			if _, ok := u.blobs[t.Digest]; !ok {
+				blobs, err := p.blobsOf(r, t)
+				if err != nil {
+					return err
+				}
+				u.blobs[t.Digest] = blobs
+			}
+			u.add(t.Digest)
+
+			if t.Digest != r.Tags[newest].Digest && !policy.Retain(p.Cfg.Policy.RetainTags, t.Name) {
+				removable = append(removable, item{repo: r, tag: t})
+			}
+		}
+	}
+
+	sort.SliceStable(removable, func(i, j int) bool {
+		return removable[i].tag.Created.Before(removable[j].tag.Created)
+	})
+
+	budget := int64(p.Cfg.Policy.SizePolicy.Budget)
+	for _, it := range removable {
+		if u.total <= budget {
+			break
+		}
+		selected.Add(it.repo, it.tag.Name)
+		u.remove(it.tag.Digest)
+	}
+
+	return nil
+}
+
+// blobsOf gets blobs of the image a tag points to.
+func (p *sizePolicyProcessor) blobsOf(r *policy.RepoTags, t policy.Tag) ([]blob, error) {
+	if !p.Cfg.Policy.SizePolicy.DedupLayers {
+		return []blob{{digest: t.Digest, size: t.Size}}, nil
+	}
+
+	layers, err := p.layers(r.Project, r.Repo, t.Name)
+	if err != nil {
+		return nil, fmt.Errorf("get manifest of %s/%s:%s error: %v", r.Project, r.Repo, t.Name, err)
+	}
+
+	var blobs []blob
+	for _, l := range layers {
+		blobs = append(blobs, blob{digest: l.Digest, size: int64(l.Size)})
+	}
+	return blobs, nil
+}
+
+func (p *sizePolicyProcessor) manifestLayers(project, repo, tag string) ([]*harbor.TagLayers, error) {
+	manifest, err := p.Client.GetTagManifest(project, repo, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest.Manifest.Layers, nil
+}
+
+// add adds a tag reference to the image.
+func (u *usage) add(digest string) {
+	u.images[digest]++
+	if u.images[digest] > 1 {
+		return
+	}
+
+	for _, b := range u.blobs[digest] {
+		u.refs[b.digest]++
+		if u.refs[b.digest] == 1 {
+			u.total += b.size
+		}
+	}
+}
+
+// remove removes a tag reference from the image, storage is released when no references left.
+func (u *usage) remove(digest string) {
+	u.images[digest]--
+	if u.images[digest] > 0 {
+		return
+	}
+
+	for _, b := range u.blobs[digest] {
+		u.refs[b.digest]--
+		if u.refs[b.digest] == 0 {
+			u.total -= b.size
+		}
+	}
+}
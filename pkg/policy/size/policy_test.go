@@ -0,0 +1,126 @@
+package size
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+	_ "github.com/cd1989/harbor-cleaner/pkg/policy/composite"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Now()
+	app := &policy.RepoTags{
+		Project: "ml",
+		Repo:    "app",
+		Tags: []policy.Tag{
+			{Name: "v4", Digest: "d4", Size: 40, Created: now},
+			{Name: "latest", Digest: "d4", Size: 40, Created: now},
+			{Name: "v3", Digest: "d3", Size: 30, Created: now.Add(-time.Hour)},
+			{Name: "v2", Digest: "d2", Size: 20, Created: now.Add(-time.Hour * 2)},
+			{Name: "v1", Digest: "d1", Size: 10, Created: now.Add(-time.Hour * 3)},
+		},
+	}
+	model := &policy.RepoTags{
+		Project: "ml",
+		Repo:    "model",
+		Tags: []policy.Tag{
+			{Name: "m2", Digest: "m2", Size: 50, Created: now.Add(-time.Minute)},
+			{Name: "m1", Digest: "m1", Size: 50, Created: now.Add(-time.Hour * 4)},
+		},
+	}
+	layers := map[string][]*harbor.TagLayers{
+		"v4": {{Digest: "base", Size: 30}, {Digest: "l4", Size: 10}},
+		"v3": {{Digest: "base", Size: 30}},
+		"v2": {{Digest: "base", Size: 30}, {Digest: "l2", Size: 10}},
+		"v1": {{Digest: "l1", Size: 10}},
+	}
+
+	cases := []struct {
+		policy   config.SizePolicy
+		repos    []*policy.RepoTags
+		expected []string
+	}{
+		{
+			config.SizePolicy{Budget: 70},
+			[]*policy.RepoTags{app},
+			[]string{"v2", "v1"},
+		},
+		{
+			config.SizePolicy{Budget: 1},
+			[]*policy.RepoTags{app},
+			[]string{"v3", "v2", "v1"},
+		},
+		{
+			config.SizePolicy{Budget: 45, DedupLayers: true},
+			[]*policy.RepoTags{app},
+			[]string{"v2", "v1"},
+		},
+		{
+			config.SizePolicy{Budget: 140, Scope: ScopeProject},
+			[]*policy.RepoTags{app, model},
+			[]string{"v1", "m1"},
+		},
+	}
+
+	for _, c := range cases {
+		p := newFactory()(config.C{Policy: config.Policy{SizePolicy: &c.policy}}).(*sizePolicyProcessor)
+		p.layers = func(project, repo, tag string) ([]*harbor.TagLayers, error) {
+			return layers[tag], nil
+		}
+		assert.Nil(t, p.Validate())
+		selected, err := p.Evaluate(c.repos)
+		assert.Nil(t, err)
+
+		var names []string
+		for _, r := range c.repos {
+			for _, tag := range r.Tags {
+				if selected.Has(r, tag.Name) {
+					names = append(names, tag.Name)
+				}
+			}
+		}
+		assert.Equal(t, c.expected, names)
+	}
+}
+
+func TestEvaluateInComposite(t *testing.T) {
+	now := time.Now()
+	app := &policy.RepoTags{
+		Project: "ml",
+		Repo:    "app",
+		Tags: []policy.Tag{
+			{Name: "v4", Digest: "d4", Size: 40, Created: now},
+			{Name: "v3", Digest: "d3", Size: 30, Created: now.Add(-time.Hour)},
+			{Name: "v2", Digest: "d2", Size: 20, Created: now.Add(-time.Hour * 2)},
+			{Name: "v1", Digest: "d1", Size: 10, Created: now.Add(-time.Hour * 3)},
+		},
+	}
+
+	// Retain tags of the composite policy apply to the leaf, so 'v2' is not counted as freed.
+	cfg := config.C{Policy: config.Policy{
+		Type: string(policy.CompositePolicy),
+		CompositePolicy: &config.Rule{Policy: &config.Policy{
+			Type:       string(policy.SizePolicy),
+			SizePolicy: &config.SizePolicy{Budget: 70},
+		}},
+		RetainTags: []string{"v2"},
+	}}
+	e, err := policy.NewEvaluator(cfg, cfg.Policy)
+	assert.Nil(t, err)
+	selected, err := e.Evaluate([]*policy.RepoTags{app})
+	assert.Nil(t, err)
+
+	candidates := policy.BuildCandidates([]*policy.RepoTags{app}, selected, cfg.Policy.RetainTags)
+	var names []string
+	for _, c := range candidates {
+		for _, tag := range c.Tags {
+			names = append(names, tag.Name)
+		}
+	}
+	assert.Equal(t, []string{"v3", "v1"}, names)
+}
@@ -2,6 +2,7 @@ package touch
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/cd1989/harbor-cleaner/pkg/config"
@@ -36,63 +37,111 @@ func (p *touchPolicyProcessor) GetPolicyType() policy.Type {
 	return policy.RecentlyNotTouchedPolicy
 }
 
+// Validate validates the policy configuration.
+func (p *touchPolicyProcessor) Validate() error {
+	if p.Cfg.Policy.NotTouchedPolicy == nil {
+		return fmt.Errorf("policy.notTouchedPolicy not configured, it's necessary when policy.type == 'recentlyNotTouched'")
+	}
+
+	if p.Cfg.Policy.NotTouchedPolicy.Time <= 0 {
+		return fmt.Errorf("policy.notTouchedPolicy.time should be positive")
+	}
+
+	return nil
+}
+
 // ListCandidates list all candidates to be remove based on the policy
 func (p *touchPolicyProcessor) ListCandidates() ([]*policy.Candidate, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
 	images, err := p.ListTags()
 	if err != nil {
 		return nil, err
 	}
 
-	if p.Cfg.Policy.NotTouchedPolicy == nil {
-		return nil, fmt.Errorf("policy.notTouchedPolicy not configured, it's necessary when policy.type == 'recentlyNotTouched'")
+	selected, err := p.Evaluate(images)
+	if err != nil {
+		return nil, err
 	}
 
+	return policy.BuildCandidates(images, selected, p.Cfg.Policy.RetainTags), nil
+}
+
+// Evaluate selects tags to remove from the given repos
+func (p *touchPolicyProcessor) Evaluate(images []*policy.RepoTags) (policy.Selection, error) {
 	endTime := time.Now().Unix()
-	startTime := endTime - p.Cfg.Policy.NotTouchedPolicy.Time
-	accessLogs, err := p.Client.ListAllAccessLogs(startTime, endTime)
+	startTime := endTime - p.Cfg.Policy.NotTouchedPolicy.Time.Seconds()
+	accessLogs, err := p.ListAccessLogs(startTime, endTime, "")
 	if err != nil {
 		return nil, err
 	}
 
+	return p.evaluate(images, accessLogs), nil
+}
+
+// evaluate selects tags not touched in the access logs.
+func (p *touchPolicyProcessor) evaluate(images []*policy.RepoTags, accessLogs []*harbor.AccessLog) policy.Selection {
+	if p.Cfg.Policy.NotTouchedPolicy.ByDigest {
+		return p.evaluateByDigest(images, accessLogs)
+	}
+
 	touchedMap := make(map[string]struct{})
 	for _, log := range accessLogs {
 		touchedMap[fmt.Sprintf("%s:%s", log.RepoName, log.Tag)] = struct{}{}
 	}
 
-	var imagesToClean []*policy.Candidate
+	selected := make(policy.Selection)
 	for _, r := range images {
-		var candidates []policy.Tag
-		var remains []policy.Tag
 		for _, t := range r.Tags {
-			if _, ok := touchedMap[fmt.Sprintf("%s/%s:%s", r.Project, r.Repo, t.Name)]; ok || policy.Retain(p.Cfg.Policy.RetainTags, t.Name) {
-				remains = append(remains, t)
-				continue
+			if _, ok := touchedMap[fmt.Sprintf("%s/%s:%s", r.Project, r.Repo, t.Name)]; !ok {
+				selected.Add(r, t.Name)
 			}
-
-			candidates = append(candidates, t)
 		}
+	}
 
-		remainsDigests := make(map[string][]string)
-		for _, t := range remains {
-			remainsDigests[t.Digest] = append(remainsDigests[t.Digest], t.Name)
+	return selected
+}
+
+// evaluateByDigest resolves access logs to digests and selects tags whose digest is not touched, so
+// that all tags of an image are regarded as touched when any of them, or the digest, is touched.
+// Tags in logs are resolved by current tags in the repo, tags already removed are ignored.
+func (p *touchPolicyProcessor) evaluateByDigest(images []*policy.RepoTags, accessLogs []*harbor.AccessLog) policy.Selection {
+	digests := make(map[string]string)
+	for _, r := range images {
+		for _, t := range r.Tags {
+			digests[fmt.Sprintf("%s/%s:%s", r.Project, r.Repo, t.Name)] = t.Digest
 		}
+	}
 
-		dangerTags := make(map[string][]string)
-		for _, t := range candidates {
-			if tags, ok := remainsDigests[t.Digest]; ok {
-				dangerTags[t.Digest] = tags
+	touchedMap := make(map[string]struct{})
+	for _, log := range accessLogs {
+		digest := log.Tag
+		if !isDigest(digest) {
+			d, ok := digests[fmt.Sprintf("%s:%s", log.RepoName, log.Tag)]
+			if !ok {
+				continue
 			}
+			digest = d
 		}
+		touchedMap[fmt.Sprintf("%s@%s", log.RepoName, digest)] = struct{}{}
+	}
 
-		if len(candidates) > 0 {
-			imagesToClean = append(imagesToClean, &policy.Candidate{
-				Project:   r.Project,
-				Repo:      r.Repo,
-				Tags:      candidates,
-				Protected: dangerTags,
-			})
+	selected := make(policy.Selection)
+	for _, r := range images {
+		for _, t := range r.Tags {
+			if _, ok := touchedMap[fmt.Sprintf("%s/%s@%s", r.Project, r.Repo, t.Digest)]; !ok {
+				selected.Add(r, t.Name)
+			}
 		}
 	}
 
-	return imagesToClean, nil
+	return selected
+}
+
+// isDigest checks whether a reference is a digest like 'sha256:...' instead of a tag. Tags can't
+// contain ':', so a reference with ':' is a digest.
+func isDigest(ref string) bool {
+	return strings.Contains(ref, ":")
 }
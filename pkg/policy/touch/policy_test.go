@@ -0,0 +1,52 @@
+package touch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/harbor"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+func TestEvaluate(t *testing.T) {
+	repo := &policy.RepoTags{
+		Project: "library",
+		Repo:    "app",
+		Tags: []policy.Tag{
+			{Name: "latest", Digest: "sha256:d3"},
+			{Name: "1.4.2", Digest: "sha256:d3"},
+			{Name: "1.4.1", Digest: "sha256:d2"},
+			{Name: "1.4.0", Digest: "sha256:d1"},
+		},
+	}
+	logs := []*harbor.AccessLog{
+		{RepoName: "library/app", Tag: "latest", Operation: harbor.AccessOperationPull},
+		{RepoName: "library/app", Tag: "sha256:d2", Operation: harbor.AccessOperationPull},
+		{RepoName: "library/app", Tag: "1.3.0", Operation: harbor.AccessOperationPull},
+	}
+
+	cases := []struct {
+		byDigest bool
+		expected []string
+	}{
+		{false, []string{"1.4.2", "1.4.1", "1.4.0"}},
+		{true, []string{"1.4.0"}},
+	}
+
+	for _, c := range cases {
+		cfg := config.C{Policy: config.Policy{NotTouchedPolicy: &config.NotTouchedPolicy{Time: 1, ByDigest: c.byDigest}}}
+		p := newFactory()(cfg).(*touchPolicyProcessor)
+		assert.Nil(t, p.Validate())
+		selected := p.evaluate([]*policy.RepoTags{repo}, logs)
+
+		var names []string
+		for _, tag := range repo.Tags {
+			if selected.Has(repo, tag.Name) {
+				names = append(names, tag.Name)
+			}
+		}
+		assert.Equal(t, c.expected, names)
+	}
+}
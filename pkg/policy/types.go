@@ -7,22 +7,62 @@ import "time"
 // It is a map from digest ID to tags list. A tag needs to be protected when it has the
 // same digest ID to those tags in 'Tags'.
 type Candidate struct {
-	Project   string
-	Repo      string
-	Tags      []Tag
-	Protected map[string][]string
+	Project   string              `json:"project"`
+	Repo      string              `json:"repo"`
+	Tags      []Tag               `json:"tags"`
+	Protected map[string][]string `json:"protected,omitempty"`
+	// Scope is name of the scoped policy applied to the repo, 'global' for the global policy
+	Scope string `json:"scope,omitempty"`
+	// Remains is number of distinct images (digests) remained in the repo after the clean
+	Remains int `json:"remains"`
 }
 
 // RepoTags defines all image tags in a repo
 type RepoTags struct {
-	Project string
-	Repo    string
-	Tags    []Tag
+	Project string `json:"project"`
+	Repo    string `json:"repo"`
+	Tags    []Tag  `json:"tags"`
 }
 
 // Tag describes an image tag
 type Tag struct {
-	Name    string
-	Digest  string
-	Created time.Time
+	Name    string    `json:"name"`
+	Digest  string    `json:"digest"`
+	Created time.Time `json:"created"`
+	Size    int64     `json:"size"`
+	// Labels are labels in the image config
+	Labels map[string]string `json:"labels,omitempty"`
+	// Architecture, OS, Author and DockerVersion are metadata in the image config
+	Architecture  string `json:"architecture,omitempty"`
+	OS            string `json:"os,omitempty"`
+	Author        string `json:"author,omitempty"`
+	DockerVersion string `json:"dockerVersion,omitempty"`
+}
+
+// NewCandidate builds candidate of a repo from tags to remove and tags to remain. Tags to remain
+// that share digest with tags to remove are protected. Nil is returned if no tags to remove.
+func NewCandidate(r *RepoTags, candidates, remains []Tag) *Candidate {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	remainsDigests := make(map[string][]string)
+	for _, t := range remains {
+		remainsDigests[t.Digest] = append(remainsDigests[t.Digest], t.Name)
+	}
+
+	dangerTags := make(map[string][]string)
+	for _, t := range candidates {
+		if tags, ok := remainsDigests[t.Digest]; ok {
+			dangerTags[t.Digest] = tags
+		}
+	}
+
+	return &Candidate{
+		Project:   r.Project,
+		Repo:      r.Repo,
+		Tags:      candidates,
+		Protected: dangerTags,
+		Remains:   len(remainsDigests),
+	}
 }
@@ -0,0 +1,417 @@
+package protect
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	listLimit         = 500
+)
+
+// workload is a kind of Kubernetes resource that runs images, 'path' is the API path to list them
+// in all namespaces, and 'fallback' is the API path of an older version used when 'path' not found.
+type workload struct {
+	kind     string
+	path     string
+	fallback string
+}
+
+var workloads = []workload{
+	{kind: "pod", path: "/api/v1/pods"},
+	{kind: "deployment", path: "/apis/apps/v1/deployments"},
+	{kind: "statefulset", path: "/apis/apps/v1/statefulsets"},
+	{kind: "daemonset", path: "/apis/apps/v1/daemonsets"},
+	{kind: "job", path: "/apis/batch/v1/jobs"},
+	{kind: "cronjob", path: "/apis/batch/v1/cronjobs", fallback: "/apis/batch/v1beta1/cronjobs"},
+}
+
+// KubernetesSource lists images used by workloads in a Kubernetes cluster through Kubernetes API.
+// Images of pods are also resolved to digests from the container statuses.
+type KubernetesSource struct {
+	name   string
+	server string
+	token  string
+	client *http.Client
+	hosts  []string
+}
+
+// Ensure (*KubernetesSource) implements interface Source
+var _ Source = (*KubernetesSource)(nil)
+
+// NewKubernetesSource creates a source from kubeconfig, or in-cluster config if kubeconfig not set.
+func NewKubernetesSource(cfg *config.KubernetesProtection, hosts []string) (*KubernetesSource, error) {
+	if len(cfg.Kubeconfig) == 0 {
+		return inClusterSource(hosts)
+	}
+
+	return kubeconfigSource(cfg.Kubeconfig, cfg.Context, hosts)
+}
+
+// Name of the source
+func (s *KubernetesSource) Name() string {
+	return s.name
+}
+
+// References lists images used by workloads in all namespaces.
+func (s *KubernetesSource) References() ([]*Reference, error) {
+	var refs []*Reference
+	for _, w := range workloads {
+		objects, err := s.list(w.path)
+		if err == errNotFound && len(w.fallback) > 0 {
+			objects, err = s.list(w.fallback)
+		}
+		if err == errNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("list %ss error: %v", w.kind, err)
+		}
+
+		for _, o := range objects {
+			source := fmt.Sprintf("%s %s %s/%s", s.name, w.kind, o.Metadata.Namespace, o.Metadata.Name)
+			for _, image := range o.images() {
+				if ref := ParseReference(image, s.hosts); ref != nil {
+					ref.Source = source
+					refs = append(refs, ref)
+				}
+			}
+		}
+	}
+
+	return refs, nil
+}
+
+var errNotFound = fmt.Errorf("not found")
+
+// list lists all objects in the API path page by page.
+func (s *KubernetesSource) list(path string) ([]*object, error) {
+	var objects []*object
+	cont := ""
+	for {
+		query := url.Values{}
+		query.Set("limit", fmt.Sprintf("%d", listLimit))
+		if len(cont) > 0 {
+			query.Set("continue", cont)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, s.server+path+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		if len(s.token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+s.token)
+		}
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, errNotFound
+		}
+		if resp.StatusCode/100 != 2 {
+			return nil, fmt.Errorf("%s: %s", resp.Status, body)
+		}
+
+		l := &objectList{}
+		if err := json.Unmarshal(body, l); err != nil {
+			return nil, err
+		}
+		objects = append(objects, l.Items...)
+
+		cont = l.Metadata.Continue
+		if len(cont) == 0 {
+			return objects, nil
+		}
+	}
+}
+
+type objectList struct {
+	Metadata struct {
+		Continue string `json:"continue"`
+	} `json:"metadata"`
+	Items []*object `json:"items"`
+}
+
+// object is a Kubernetes workload, only fields about images are decoded. Pod spec is 'spec' for
+// pods, 'spec.template.spec' for controllers and 'spec.jobTemplate.spec.template.spec' for cronjobs.
+type object struct {
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Spec struct {
+		podSpec
+		Template *struct {
+			Spec podSpec `json:"spec"`
+		} `json:"template"`
+		JobTemplate *struct {
+			Spec struct {
+				Template struct {
+					Spec podSpec `json:"spec"`
+				} `json:"template"`
+			} `json:"spec"`
+		} `json:"jobTemplate"`
+	} `json:"spec"`
+	Status struct {
+		ContainerStatuses     []containerStatus `json:"containerStatuses"`
+		InitContainerStatuses []containerStatus `json:"initContainerStatuses"`
+	} `json:"status"`
+}
+
+type podSpec struct {
+	Containers     []container `json:"containers"`
+	InitContainers []container `json:"initContainers"`
+}
+
+type container struct {
+	Image string `json:"image"`
+}
+
+type containerStatus struct {
+	Image   string `json:"image"`
+	ImageID string `json:"imageID"`
+}
+
+// images gets images referenced by the object, image IDs of running containers are included.
+func (o *object) images() []string {
+	var images []string
+	specs := []podSpec{o.Spec.podSpec}
+	if o.Spec.Template != nil {
+		specs = append(specs, o.Spec.Template.Spec)
+	}
+	if o.Spec.JobTemplate != nil {
+		specs = append(specs, o.Spec.JobTemplate.Spec.Template.Spec)
+	}
+	for _, spec := range specs {
+		for _, c := range append(spec.Containers, spec.InitContainers...) {
+			images = append(images, c.Image)
+		}
+	}
+
+	for _, status := range append(o.Status.ContainerStatuses, o.Status.InitContainerStatuses...) {
+		// Image ID is like 'docker-pullable://<image>@sha256:...' or '<image>@sha256:...', while
+		// 'docker://sha256:...' is ID of local image, which is not a reference.
+		id := strings.TrimPrefix(status.ImageID, "docker-pullable://")
+		if strings.Contains(id, "@") && !strings.Contains(id, "://") {
+			images = append(images, id)
+		}
+	}
+
+	return images
+}
+
+// inClusterSource creates source with service account of the pod the cleaner runs in.
+func inClusterSource(hosts []string) (*KubernetesSource, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if len(host) == 0 || len(port) == 0 {
+		return nil, fmt.Errorf("not running in a Kubernetes cluster, kubeconfig should be configured")
+	}
+
+	token, err := ioutil.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, err
+	}
+	ca, err := ioutil.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{RootCAs: x509.NewCertPool()}
+	if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("invalid CA certificate in %s/ca.crt", serviceAccountDir)
+	}
+
+	return &KubernetesSource{
+		name:   "kubernetes(in-cluster)",
+		server: "https://" + net.JoinHostPort(host, port),
+		token:  strings.TrimSpace(string(token)),
+		client: newHTTPClient(tlsConfig),
+		hosts:  hosts,
+	}, nil
+}
+
+// kubeconfig is the part of kubeconfig file used by the cleaner. Authentication by token and client
+// certificate are supported, while auth providers and exec plugins are not. Relative file paths are
+// relative to the directory of the kubeconfig file, as kubectl does.
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string `yaml:"token"`
+			TokenFile             string `yaml:"tokenFile"`
+			ClientCertificate     string `yaml:"client-certificate"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKey             string `yaml:"client-key"`
+			ClientKeyData         string `yaml:"client-key-data"`
+			// Exec and AuthProvider are only checked to report they're not supported
+			Exec         interface{} `yaml:"exec"`
+			AuthProvider interface{} `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// kubeconfigSource creates source from the context in kubeconfig file.
+func kubeconfigSource(file, context string, hosts []string) (*KubernetesSource, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	kc := &kubeconfig{}
+	if err := yaml.Unmarshal(b, kc); err != nil {
+		return nil, fmt.Errorf("unmarshal kubeconfig %s error: %v", file, err)
+	}
+
+	dir := filepath.Dir(file)
+
+	if len(context) == 0 {
+		context = kc.CurrentContext
+	}
+	var clusterName, userName string
+	found := false
+	for _, c := range kc.Contexts {
+		if c.Name == context {
+			clusterName, userName, found = c.Context.Cluster, c.Context.User, true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("context '%s' not found in kubeconfig %s", context, file)
+	}
+
+	s := &KubernetesSource{
+		name:  fmt.Sprintf("kubernetes(%s)", context),
+		hosts: hosts,
+	}
+	tlsConfig := &tls.Config{}
+	for _, c := range kc.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		s.server = strings.TrimRight(c.Cluster.Server, "/")
+		tlsConfig.InsecureSkipVerify = c.Cluster.InsecureSkipTLSVerify
+		ca, err := fileOrData(resolvePath(dir, c.Cluster.CertificateAuthority), c.Cluster.CertificateAuthorityData)
+		if err != nil {
+			return nil, err
+		}
+		if len(ca) > 0 {
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("invalid CA certificate of cluster '%s'", clusterName)
+			}
+		}
+	}
+	if len(s.server) == 0 {
+		return nil, fmt.Errorf("server of cluster '%s' not found in kubeconfig %s", clusterName, file)
+	}
+
+	found = false
+	for _, u := range kc.Users {
+		if u.Name != userName {
+			continue
+		}
+		found = true
+		if u.User.Exec != nil {
+			return nil, fmt.Errorf("exec plugin of user '%s' in kubeconfig %s not supported, use token or client certificate", userName, file)
+		}
+		if u.User.AuthProvider != nil {
+			return nil, fmt.Errorf("auth provider of user '%s' in kubeconfig %s not supported, use token or client certificate", userName, file)
+		}
+
+		s.token = u.User.Token
+		if len(u.User.TokenFile) > 0 {
+			token, err := ioutil.ReadFile(resolvePath(dir, u.User.TokenFile))
+			if err != nil {
+				return nil, err
+			}
+			s.token = strings.TrimSpace(string(token))
+		}
+
+		cert, err := fileOrData(resolvePath(dir, u.User.ClientCertificate), u.User.ClientCertificateData)
+		if err != nil {
+			return nil, err
+		}
+		key, err := fileOrData(resolvePath(dir, u.User.ClientKey), u.User.ClientKeyData)
+		if err != nil {
+			return nil, err
+		}
+		if len(cert) > 0 && len(key) > 0 {
+			pair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid client certificate of user '%s': %v", userName, err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{pair}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("user '%s' not found in kubeconfig %s", userName, file)
+	}
+	s.client = newHTTPClient(tlsConfig)
+
+	return s, nil
+}
+
+// resolvePath resolves path relative to the directory, absolute and empty paths are kept as they are.
+func resolvePath(dir, path string) string {
+	if len(path) == 0 || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// fileOrData reads content from file if given, otherwise decodes the base64 data.
+func fileOrData(file, data string) ([]byte, error) {
+	if len(file) > 0 {
+		return ioutil.ReadFile(file)
+	}
+	if len(data) > 0 {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	return nil, nil
+}
+
+func newHTTPClient(tlsConfig *tls.Config) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		Timeout:   time.Minute,
+	}
+}
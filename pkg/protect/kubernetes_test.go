@@ -0,0 +1,166 @@
+package protect
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+)
+
+const kubeconfigTemplate = `
+apiVersion: v1
+kind: Config
+current-context: test
+clusters:
+- name: test
+  cluster:
+    server: %s
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: admin
+users:
+- name: admin
+  user:
+    token: secret
+`
+
+func TestKubernetesReferences(t *testing.T) {
+	responses := map[string]string{
+		"/api/v1/pods": `{"metadata": {"continue": "next"}, "items": [{
+			"metadata": {"name": "web-1", "namespace": "default"},
+			"spec": {"containers": [{"image": "harbor.example.com/library/web:v1"}, {"image": "nginx:1.17"}]},
+			"status": {"containerStatuses": [{"imageID": "docker-pullable://harbor.example.com/library/web@sha256:abc"}]}
+		}]}`,
+		"/api/v1/pods?next": `{"metadata": {}, "items": [{
+			"metadata": {"name": "job-1", "namespace": "ci"},
+			"spec": {"initContainers": [{"image": "harbor.example.com/ci/init:v2"}]}
+		}]}`,
+		"/apis/apps/v1/deployments": `{"metadata": {}, "items": [{
+			"metadata": {"name": "web", "namespace": "default"},
+			"spec": {"template": {"spec": {"containers": [{"image": "harbor.example.com/library/web:v1"}]}}}
+		}]}`,
+		"/apis/batch/v1beta1/cronjobs": `{"metadata": {}, "items": [{
+			"metadata": {"name": "backup", "namespace": "ops"},
+			"spec": {"jobTemplate": {"spec": {"template": {"spec": {"containers": [{"image": "harbor.example.com/ops/backup@sha256:def"}]}}}}}
+		}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		key := r.URL.Path
+		if c := r.URL.Query().Get("continue"); len(c) > 0 {
+			key += "?" + c
+		}
+		body, ok := responses[key]
+		if !ok {
+			if r.URL.Path == "/apis/batch/v1/cronjobs" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			body = `{"metadata": {}, "items": []}`
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "config")
+	assert.Nil(t, ioutil.WriteFile(file, []byte(fmt.Sprintf(kubeconfigTemplate, server.URL)), 0644))
+
+	s, err := NewKubernetesSource(&config.KubernetesProtection{Kubeconfig: file}, []string{"harbor.example.com"})
+	assert.Nil(t, err)
+	refs, err := s.References()
+	assert.Nil(t, err)
+
+	var images []string
+	for _, r := range refs {
+		images = append(images, fmt.Sprintf("%s/%s:%s@%s %s", r.Project, r.Repo, r.Tag, r.Digest, r.Source))
+	}
+	assert.Equal(t, []string{
+		"library/web:v1@ kubernetes(test) pod default/web-1",
+		"library/web:@sha256:abc kubernetes(test) pod default/web-1",
+		"ci/init:v2@ kubernetes(test) pod ci/job-1",
+		"library/web:v1@ kubernetes(test) deployment default/web",
+		"ops/backup:@sha256:def kubernetes(test) cronjob ops/backup",
+	}, images)
+}
+
+func TestKubeconfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"metadata": {}, "items": []}`))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "certs"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "certs", "ca.crt"), ca, 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "token"), []byte("secret\n"), 0644))
+
+	cases := []struct {
+		user  string
+		valid bool
+	}{
+		// Files relative to the directory of kubeconfig
+		{"tokenFile: token", true},
+		{"exec: {command: aws}", false},
+		{"auth-provider: {name: gcp}", false},
+	}
+	for _, c := range cases {
+		content := fmt.Sprintf(`
+current-context: test
+clusters:
+- name: test
+  cluster:
+    server: %s
+    certificate-authority: certs/ca.crt
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: admin
+- name: unknown-user
+  context:
+    cluster: test
+    user: unknown
+users:
+- name: admin
+  user:
+    %s
+`, server.URL, c.user)
+		file := filepath.Join(dir, "config")
+		assert.Nil(t, ioutil.WriteFile(file, []byte(content), 0644))
+
+		s, err := kubeconfigSource(file, "", nil)
+		if !c.valid {
+			assert.NotNil(t, err, c.user)
+			continue
+		}
+		assert.Nil(t, err)
+		_, err = s.References()
+		assert.Nil(t, err)
+
+		_, err = kubeconfigSource(file, "unknown-user", nil)
+		assert.NotNil(t, err)
+	}
+}
@@ -0,0 +1,165 @@
+package protect
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// manifestExts are extensions of files scanned in the manifest directory.
+var manifestExts = map[string]bool{
+	".yaml": true,
+	".yml":  true,
+	".json": true,
+}
+
+// ManifestSource scans a directory tree of deployment manifests for image references, including
+// Kubernetes manifests, Helm values files, Kustomize 'images' entries and docker-compose files.
+type ManifestSource struct {
+	dir     string
+	hosts   []string
+	pattern *regexp.Regexp
+}
+
+// Ensure (*ManifestSource) implements interface Source
+var _ Source = (*ManifestSource)(nil)
+
+// NewManifestSource creates a source that scans the given directory.
+func NewManifestSource(dir string, hosts []string) *ManifestSource {
+	var quoted []string
+	for _, h := range hosts {
+		quoted = append(quoted, regexp.QuoteMeta(h))
+	}
+
+	return &ManifestSource{
+		dir:     dir,
+		hosts:   hosts,
+		pattern: regexp.MustCompile(`(?i)(` + strings.Join(quoted, "|") + `)/[A-Za-z0-9._/-]+(:[A-Za-z0-9._-]+)?(@sha256:[a-f0-9]+)?`),
+	}
+}
+
+// Name of the source
+func (s *ManifestSource) Name() string {
+	return fmt.Sprintf("manifests(%s)", s.dir)
+}
+
+// References scans all manifest files in the directory, hidden directories like '.git' are skipped.
+// Source of a reference is the path of the file it's found in.
+func (s *ManifestSource) References() ([]*Reference, error) {
+	var refs []*Reference
+	err := filepath.Walk(s.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != s.dir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !manifestExts[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, image := range s.images(path, b) {
+			if ref := ParseReference(image, s.hosts); ref != nil {
+				ref.Source = path
+				refs = append(refs, ref)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return refs, nil
+}
+
+// images gets image references in a manifest file. Files that are not valid YAML, e.g. Helm
+// templates, are scanned as text for references on Harbor hosts.
+func (s *ManifestSource) images(path string, b []byte) []string {
+	var images []string
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			return images
+		}
+		if err != nil {
+			logrus.Debugf("Parse %s error: %v, scan it as text", path, err)
+			return s.pattern.FindAllString(string(b), -1)
+		}
+		images = append(images, walk(doc)...)
+	}
+}
+
+// walk collects image references in a YAML document. It recognizes:
+// - 'image: <reference>', used in Kubernetes manifests, docker-compose files and Helm values
+// - 'repository' with optional 'registry', 'tag' and 'digest', used in Helm values
+// - 'name' with 'newName', 'newTag' or 'digest', used in Kustomize 'images' entries
+func walk(v interface{}) []string {
+	var images []string
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		if image, ok := v["image"].(string); ok {
+			images = append(images, image)
+		}
+		if repo, ok := v["repository"].(string); ok {
+			if registry := scalar(v["registry"]); len(registry) > 0 {
+				repo = registry + "/" + repo
+			}
+			images = append(images, reference(repo, scalar(v["tag"]), scalar(v["digest"])))
+		}
+		if name, ok := v["name"].(string); ok && (v["newName"] != nil || v["newTag"] != nil || v["digest"] != nil) {
+			if newName := scalar(v["newName"]); len(newName) > 0 {
+				name = newName
+			}
+			images = append(images, reference(name, scalar(v["newTag"]), scalar(v["digest"])))
+		}
+		for _, child := range v {
+			images = append(images, walk(child)...)
+		}
+	case []interface{}:
+		for _, child := range v {
+			images = append(images, walk(child)...)
+		}
+	}
+
+	return images
+}
+
+func reference(name, tag, digest string) string {
+	if len(digest) > 0 {
+		return name + "@" + digest
+	}
+	if len(tag) > 0 {
+		return name + ":" + tag
+	}
+	return name
+}
+
+// scalar converts a YAML scalar to string, e.g. 'tag: 1.0' is decoded as number.
+func scalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil, map[interface{}]interface{}, []interface{}:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
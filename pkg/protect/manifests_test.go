@@ -0,0 +1,34 @@
+package protect
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestReferences(t *testing.T) {
+	dir := filepath.Join("testdata", "manifests")
+	s := NewManifestSource(dir, []string{"harbor.example.com"})
+	refs, err := s.References()
+	assert.Nil(t, err)
+
+	var images []string
+	for _, r := range refs {
+		rel, err := filepath.Rel(dir, r.Source)
+		assert.Nil(t, err)
+		images = append(images, r.Project+"/"+r.Repo+":"+r.Tag+"@"+r.Digest+" "+filepath.ToSlash(rel))
+	}
+	sort.Strings(images)
+
+	assert.Equal(t, []string{
+		"infra/postgres:11@ compose/docker-compose.yml",
+		"library/api:2.3.0@ helm/values.yaml",
+		"library/sidecar:v5@ helm/deployment.yaml",
+		"library/web-next:@sha256:def kustomize/kustomization.yml",
+		"library/web:v1@ k8s/deploy.yaml",
+		"library/web:v2@ kustomize/kustomization.yml",
+		"library/worker:@sha256:abc helm/values.yaml",
+	}, images)
+}
@@ -0,0 +1,211 @@
+package protect
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/cd1989/harbor-cleaner/pkg/config"
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+// Reference is an image on Harbor referenced by a protection source, by tag or by digest or both.
+type Reference struct {
+	Project string
+	Repo    string
+	Tag     string
+	Digest  string
+	// Source describes where the reference is found, e.g. 'kubernetes deployment default/web'
+	Source string
+}
+
+// Source lists image references that should never be cleaned.
+type Source interface {
+	// Name of the source shown in logs
+	Name() string
+	// References lists image references, references to images not on Harbor are ignored.
+	References() ([]*Reference, error)
+}
+
+// Exclusion is a tag excluded from candidates because it's referenced by a protection source.
+type Exclusion struct {
+	Project string `json:"project"`
+	Repo    string `json:"repo"`
+	Tag     string `json:"tag"`
+	Digest  string `json:"digest"`
+	Source  string `json:"source"`
+}
+
+// NewSources creates protection sources configured in the config.
+func NewSources(cfg config.C) ([]Source, error) {
+	if cfg.Protection == nil {
+		return nil, nil
+	}
+
+	hosts := Hosts(cfg)
+	var sources []Source
+	for _, k := range cfg.Protection.Kubernetes {
+		s, err := NewKubernetesSource(k, hosts)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, s)
+	}
+	for _, dir := range cfg.Protection.ManifestDirs {
+		sources = append(sources, NewManifestSource(dir, hosts))
+	}
+
+	return sources, nil
+}
+
+// Hosts gets registry hosts of Harbor, it's the host of Harbor and extra hosts configured.
+func Hosts(cfg config.C) []string {
+	host := cfg.Host
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	host = strings.TrimRight(host, "/")
+
+	hosts := []string{host}
+	if cfg.Protection != nil {
+		hosts = append(hosts, cfg.Protection.Hosts...)
+	}
+
+	return hosts
+}
+
+// Collect lists references from all sources, it fails if any source fails, since cleaning without
+// complete references may delete images in use.
+func Collect(sources []Source) ([]*Reference, error) {
+	var refs []*Reference
+	for _, s := range sources {
+		r, err := s.References()
+		if err != nil {
+			return nil, fmt.Errorf("list image references from %s error: %v", s.Name(), err)
+		}
+		logrus.Infof("%d image references found in %s", len(r), s.Name())
+		refs = append(refs, r...)
+	}
+
+	return refs, nil
+}
+
+// ParseReference parses an image reference like 'harbor.example.com/library/app:v1' or
+// 'harbor.example.com/library/app@sha256:...'. Nil is returned if the image is not on any of the
+// given hosts. Tag defaults to 'latest' if neither tag nor digest given.
+func ParseReference(image string, hosts []string) *Reference {
+	image = strings.TrimSpace(image)
+	slash := strings.Index(image, "/")
+	if slash < 0 || !matchHost(image[:slash], hosts) {
+		return nil
+	}
+	name := image[slash+1:]
+
+	ref := &Reference{}
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, ":"); i >= 0 && !strings.Contains(name[i:], "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+	}
+	if len(ref.Tag) == 0 && len(ref.Digest) == 0 {
+		ref.Tag = "latest"
+	}
+
+	i := strings.Index(name, "/")
+	if i <= 0 || i == len(name)-1 {
+		return nil
+	}
+	ref.Project, ref.Repo = name[:i], name[i+1:]
+
+	return ref
+}
+
+func matchHost(host string, hosts []string) bool {
+	for _, h := range hosts {
+		if strings.EqualFold(host, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply excludes referenced tags from candidates. A tag is excluded if it's referenced by name, or
+// its image is referenced by digest or by another tag, so that the image is kept as a whole.
+// Candidates without tags left are dropped.
+func Apply(candidates []*policy.Candidate, refs []*Reference) ([]*policy.Candidate, []*Exclusion) {
+	byRepo := make(map[string][]*Reference)
+	for _, r := range refs {
+		key := r.Project + "/" + r.Repo
+		byRepo[key] = append(byRepo[key], r)
+	}
+
+	var result []*policy.Candidate
+	var exclusions []*Exclusion
+	for _, c := range candidates {
+		repoRefs := byRepo[c.Project+"/"+c.Repo]
+		if len(repoRefs) == 0 {
+			result = append(result, c)
+			continue
+		}
+
+		// Digests of images in use and the source referencing them
+		inUse := make(map[string]string)
+		for _, r := range repoRefs {
+			if len(r.Digest) > 0 {
+				inUse[r.Digest] = r.Source
+				continue
+			}
+			for _, t := range c.Tags {
+				if t.Name == r.Tag {
+					inUse[t.Digest] = r.Source
+				}
+			}
+			for digest, protected := range c.Protected {
+				for _, name := range protected {
+					if name == r.Tag {
+						inUse[digest] = r.Source
+					}
+				}
+			}
+		}
+
+		var tags []policy.Tag
+		kept := make(map[string]bool)
+		for _, t := range c.Tags {
+			source, ok := inUse[t.Digest]
+			if !ok {
+				tags = append(tags, t)
+				continue
+			}
+
+			exclusions = append(exclusions, &Exclusion{
+				Project: c.Project,
+				Repo:    c.Repo,
+				Tag:     t.Name,
+				Digest:  t.Digest,
+				Source:  source,
+			})
+			kept[t.Digest] = true
+		}
+
+		// Images kept as a whole need no protection, and they are counted in remains if not yet.
+		for digest := range kept {
+			if _, ok := c.Protected[digest]; ok {
+				delete(c.Protected, digest)
+			} else {
+				c.Remains++
+			}
+		}
+
+		c.Tags = tags
+		if len(tags) > 0 {
+			result = append(result, c)
+		}
+	}
+
+	return result, exclusions
+}
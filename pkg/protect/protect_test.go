@@ -0,0 +1,69 @@
+package protect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cd1989/harbor-cleaner/pkg/policy"
+)
+
+func TestParseReference(t *testing.T) {
+	hosts := []string{"harbor.example.com", "harbor.example.com:5000"}
+	cases := []struct {
+		image    string
+		expected *Reference
+	}{
+		{"harbor.example.com/library/app:v1", &Reference{Project: "library", Repo: "app", Tag: "v1"}},
+		{"Harbor.example.com/library/team/app", &Reference{Project: "library", Repo: "team/app", Tag: "latest"}},
+		{"harbor.example.com:5000/library/app@sha256:abc", &Reference{Project: "library", Repo: "app", Digest: "sha256:abc"}},
+		{"harbor.example.com/library/app:v1@sha256:abc", &Reference{Project: "library", Repo: "app", Tag: "v1", Digest: "sha256:abc"}},
+		{"docker.io/library/app:v1", nil},
+		{"library/app:v1", nil},
+		{"harbor.example.com/app:v1", nil},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, ParseReference(c.image, hosts), c.image)
+	}
+}
+
+func TestApply(t *testing.T) {
+	candidates := []*policy.Candidate{
+		{
+			Project: "library",
+			Repo:    "app",
+			Tags: []policy.Tag{
+				{Name: "v1", Digest: "d1"},
+				{Name: "v1-rc", Digest: "d1"},
+				{Name: "v2", Digest: "d2"},
+				{Name: "v3", Digest: "d3"},
+			},
+			Protected: map[string][]string{"d2": {"stable"}},
+			Remains:   2,
+		},
+		{
+			Project: "library",
+			Repo:    "tool",
+			Tags:    []policy.Tag{{Name: "v1", Digest: "t1"}},
+			Remains: 1,
+		},
+	}
+	refs := []*Reference{
+		{Project: "library", Repo: "app", Tag: "v1-rc", Source: "deploy.yaml"},
+		{Project: "library", Repo: "app", Tag: "stable", Source: "values.yaml"},
+		{Project: "library", Repo: "tool", Digest: "t1", Source: "pod default/tool"},
+	}
+
+	result, excluded := Apply(candidates, refs)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, []policy.Tag{{Name: "v3", Digest: "d3"}}, result[0].Tags)
+	assert.Equal(t, 0, len(result[0].Protected))
+	assert.Equal(t, 3, result[0].Remains)
+
+	var sources []string
+	for _, e := range excluded {
+		sources = append(sources, e.Repo+":"+e.Tag+" "+e.Source)
+	}
+	assert.Equal(t, []string{"app:v1 deploy.yaml", "app:v1-rc deploy.yaml", "app:v2 values.yaml", "tool:v1 pod default/tool"}, sources)
+}
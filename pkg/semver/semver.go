@@ -0,0 +1,159 @@
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version, see https://semver.org. It's parsed from image tags, so an
+// optional 'v' prefix is allowed.
+type Version struct {
+	Major      int64
+	Minor      int64
+	Patch      int64
+	PreRelease []string
+	Build      string
+	// Original is the string the version parsed from
+	Original string
+}
+
+// Parse parses a semantic version like '1.2.3', 'v1.2.3-rc.1' or '1.2.3+build.5'.
+func Parse(s string) (*Version, error) {
+	v := &Version{Original: s}
+	rest := strings.TrimPrefix(s, "v")
+
+	if i := strings.Index(rest, "+"); i >= 0 {
+		v.Build = rest[i+1:]
+		rest = rest[:i]
+		if !validIdentifiers(v.Build, false) {
+			return nil, fmt.Errorf("invalid build metadata in version '%s'", s)
+		}
+	}
+
+	if i := strings.Index(rest, "-"); i >= 0 {
+		pre := rest[i+1:]
+		rest = rest[:i]
+		if !validIdentifiers(pre, true) {
+			return nil, fmt.Errorf("invalid pre-release in version '%s'", s)
+		}
+		v.PreRelease = strings.Split(pre, ".")
+	}
+
+	parts := strings.Split(rest, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid version '%s', 'MAJOR.MINOR.PATCH' expected", s)
+	}
+
+	nums := make([]int64, 3)
+	for i, part := range parts {
+		if !isNumeric(part) || (len(part) > 1 && part[0] == '0') {
+			return nil, fmt.Errorf("invalid version '%s', '%s' is not a valid number", s, part)
+		}
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version '%s': %v", s, err)
+		}
+		nums[i] = n
+	}
+	v.Major, v.Minor, v.Patch = nums[0], nums[1], nums[2]
+
+	return v, nil
+}
+
+// IsPreRelease checks whether it's a pre-release version.
+func (v *Version) IsPreRelease() bool {
+	return len(v.PreRelease) > 0
+}
+
+// Compare compares precedence of two versions, it returns -1, 0, 1 if v is lower than, equal to or
+// higher than o. Build metadata is ignored.
+func (v *Version) Compare(o *Version) int {
+	if c := compareInt(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+
+	// A pre-release version has lower precedence than a normal version
+	if len(v.PreRelease) == 0 || len(o.PreRelease) == 0 {
+		return compareInt(int64(len(o.PreRelease)), int64(len(v.PreRelease)))
+	}
+
+	for i := 0; i < len(v.PreRelease) && i < len(o.PreRelease); i++ {
+		if c := compareIdentifier(v.PreRelease[i], o.PreRelease[i]); c != 0 {
+			return c
+		}
+	}
+
+	return compareInt(int64(len(v.PreRelease)), int64(len(o.PreRelease)))
+}
+
+func (v *Version) String() string {
+	return v.Original
+}
+
+// compareIdentifier compares pre-release identifiers, numeric identifiers are compared numerically
+// and have lower precedence than alphanumeric ones.
+func compareIdentifier(a, b string) int {
+	an, bn := isNumeric(a), isNumeric(b)
+	switch {
+	case an && bn:
+		if len(a) != len(b) {
+			return compareInt(int64(len(a)), int64(len(b)))
+		}
+		return strings.Compare(a, b)
+	case an:
+		return -1
+	case bn:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func isNumeric(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// validIdentifiers checks dot separated identifiers, they should be non-empty alphanumerics or
+// hyphens. Numeric pre-release identifiers must not have leading zeros.
+func validIdentifiers(s string, preRelease bool) bool {
+	for _, id := range strings.Split(s, ".") {
+		if len(id) == 0 {
+			return false
+		}
+		for _, c := range id {
+			if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '-') {
+				return false
+			}
+		}
+		if preRelease && isNumeric(id) && len(id) > 1 && id[0] == '0' {
+			return false
+		}
+	}
+	return true
+}
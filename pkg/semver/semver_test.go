@@ -0,0 +1,41 @@
+package semver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	v, err := Parse("v1.2.3-rc.1+build.5")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), v.Major)
+	assert.Equal(t, int64(2), v.Minor)
+	assert.Equal(t, int64(3), v.Patch)
+	assert.Equal(t, []string{"rc", "1"}, v.PreRelease)
+	assert.Equal(t, "build.5", v.Build)
+	assert.True(t, v.IsPreRelease())
+
+	for _, s := range []string{"latest", "1.2", "1.2.3.4", "01.2.3", "1.2.3-", "1.2.3-01", "1.2.3+", "1.2.x"} {
+		_, err := Parse(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// Ordered by precedence as the example in semver spec
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2",
+		"1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "v1.0.1", "1.2.0", "2.0.0",
+	}
+	for i := 0; i < len(ordered)-1; i++ {
+		a, _ := Parse(ordered[i])
+		b, _ := Parse(ordered[i+1])
+		assert.Equal(t, -1, a.Compare(b), "%s < %s", ordered[i], ordered[i+1])
+		assert.Equal(t, 1, b.Compare(a), "%s > %s", ordered[i+1], ordered[i])
+	}
+
+	a, _ := Parse("1.0.0+a")
+	b, _ := Parse("v1.0.0+b")
+	assert.Equal(t, 0, a.Compare(b))
+}
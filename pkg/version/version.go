@@ -0,0 +1,11 @@
+package version
+
+// These variables are set by the linker when building, see Makefile.
+var (
+	// VERSION is version of the cleaner, e.g. v0.4.0
+	VERSION = "UNKNOWN"
+	// COMMIT is the git commit the cleaner built from
+	COMMIT = "UNKNOWN"
+	// REPOROOT is root import path of the repo
+	REPOROOT = "github.com/cd1989/harbor-cleaner"
+)